@@ -481,6 +481,53 @@ func (c *Client) SubmitFeeBumpTransactionWithOptions(transaction *txnbuild.FeeBu
 	return c.SubmitTransactionXDR(txeBase64)
 }
 
+// SubmitFeeBumpTransactionAndWait submits a fee bump transaction to the network and waits
+// until it's included in a ledger. If Horizon times out before the transaction is ingested,
+// the transaction is polled by its hash and the same envelope is resubmitted while it isn't
+// found, until it's included in a ledger or ctx is done. err can be either an error object or
+// a horizon.Error object.
+//
+// See https://developers.stellar.org/api/resources/transactions/post/
+func (c *Client) SubmitFeeBumpTransactionAndWait(ctx context.Context, transaction *txnbuild.FeeBumpTransaction, networkPassphrase string, opts SubmitTxOpts) (tx hProtocol.Transaction, err error) {
+	tx, err = c.SubmitFeeBumpTransactionWithOptions(transaction, opts)
+	if err == nil || !isTimeoutError(err) {
+		return
+	}
+
+	txHash, hashErr := transaction.HashHex(networkPassphrase)
+	if hashErr != nil {
+		err = errors.Wrap(hashErr, "Unable to hash fee bump transaction")
+		return
+	}
+	txeBase64, encodeErr := transaction.Base64()
+	if encodeErr != nil {
+		err = errors.Wrap(encodeErr, "Unable to convert transaction object to base64 string")
+		return
+	}
+
+	ticker := time.NewTicker(transactionPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			err = errors.Wrapf(ctx.Err(), "waiting for transaction %s", txHash)
+			return
+		case <-ticker.C:
+			tx, err = c.TransactionDetail(txHash)
+			if err == nil || !IsNotFoundError(err) {
+				return
+			}
+
+			// the transaction may never have reached core, Horizon recommends
+			// resubmitting it after a timeout
+			tx, err = c.SubmitTransactionXDR(txeBase64)
+			if err == nil || !isTimeoutError(err) {
+				return
+			}
+		}
+	}
+}
+
 // SubmitTransaction submits a transaction to the network. err can be either an
 // error object or a horizon.Error object.
 //
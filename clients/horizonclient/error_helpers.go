@@ -39,3 +39,15 @@ func GetError(err error) *Error {
 
 	return hErr
 }
+
+// isTimeoutError returns true if the error is a horizonclient.Error with
+// a timeout problem indicating that Horizon gave up waiting for the request
+// to complete, e.g. a transaction that was submitted but not yet ingested.
+func isTimeoutError(err error) bool {
+	hErr := GetError(err)
+	if hErr == nil {
+		return false
+	}
+
+	return hErr.Problem.Type == "https://stellar.org/horizon-errors/timeout"
+}
@@ -117,6 +117,10 @@ var (
 	WeekResolution = time.Duration(168 * time.Hour)
 )
 
+// transactionPollInterval is how often SubmitFeeBumpTransactionAndWait checks
+// if a transaction that timed out on submission was included in a ledger.
+var transactionPollInterval = time.Second
+
 // HTTP represents the HTTP client that a horizon client uses to communicate
 type HTTP interface {
 	Do(req *http.Request) (resp *http.Response, err error)
@@ -190,6 +194,7 @@ type ClientInterface interface {
 	SubmitFeeBumpTransactionWithOptions(transaction *txnbuild.FeeBumpTransaction, opts SubmitTxOpts) (hProtocol.Transaction, error)
 	SubmitTransactionWithOptions(transaction *txnbuild.Transaction, opts SubmitTxOpts) (hProtocol.Transaction, error)
 	SubmitFeeBumpTransaction(transaction *txnbuild.FeeBumpTransaction) (hProtocol.Transaction, error)
+	SubmitFeeBumpTransactionAndWait(ctx context.Context, transaction *txnbuild.FeeBumpTransaction, networkPassphrase string, opts SubmitTxOpts) (hProtocol.Transaction, error)
 	SubmitTransaction(transaction *txnbuild.Transaction) (hProtocol.Transaction, error)
 	Transactions(request TransactionRequest) (hProtocol.TransactionsPage, error)
 	TransactionDetail(txHash string) (hProtocol.Transaction, error)
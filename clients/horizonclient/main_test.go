@@ -1,6 +1,7 @@
 package horizonclient
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
@@ -1053,6 +1054,149 @@ func TestSubmitFeeBumpTransaction(t *testing.T) {
 	assert.Equal(t, ErrAccountRequiresMemo, errors.Cause(err))
 }
 
+func TestSubmitFeeBumpTransactionAndWait(t *testing.T) {
+	defer func(interval time.Duration) { transactionPollInterval = interval }(transactionPollInterval)
+	transactionPollInterval = time.Millisecond
+
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+	}
+
+	kp := keypair.MustParseFull("SA26PHIKZM6CXDGR472SSGUQQRYXM6S437ZNHZGRM6QA4FOPLLLFRGDX")
+	sourceAccount := txnbuild.NewSimpleAccount(kp.Address(), int64(0))
+
+	tx, err := txnbuild.NewTransaction(
+		txnbuild.TransactionParams{
+			SourceAccount:        &sourceAccount,
+			IncrementSequenceNum: true,
+			Operations: []txnbuild.Operation{&txnbuild.Payment{
+				Destination: kp.Address(),
+				Amount:      "10",
+				Asset:       txnbuild.NativeAsset{},
+			}},
+			BaseFee:       txnbuild.MinBaseFee,
+			Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewTimebounds(0, 10)},
+		},
+	)
+	assert.NoError(t, err)
+	tx, err = tx.Sign(network.TestNetworkPassphrase, kp)
+	assert.NoError(t, err)
+
+	feeBumpKP := keypair.MustParseFull("SA5ZEFDVFZ52GRU7YUGR6EDPBNRU2WLA6IQFQ7S2IH2DG3VFV3DOMV2Q")
+	feeBumpTx, err := txnbuild.NewFeeBumpTransaction(txnbuild.FeeBumpTransactionParams{
+		Inner:      tx,
+		FeeAccount: feeBumpKP.Address(),
+		BaseFee:    txnbuild.MinBaseFee * 2,
+	})
+	assert.NoError(t, err)
+	feeBumpTx, err = feeBumpTx.Sign(network.TestNetworkPassphrase, feeBumpKP)
+	assert.NoError(t, err)
+	feeBumpTxHash, err := feeBumpTx.HashHex(network.TestNetworkPassphrase)
+	assert.NoError(t, err)
+
+	opts := SubmitTxOpts{SkipMemoRequiredCheck: true}
+
+	// included in a ledger on submission
+	hmock.On(
+		"POST",
+		"https://localhost/transactions",
+	).ReturnString(http.StatusOK, txSuccess)
+
+	resp, err := client.SubmitFeeBumpTransactionAndWait(context.Background(), feeBumpTx, network.TestNetworkPassphrase, opts)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int32(354811), resp.Ledger)
+	}
+
+	feeBumpTxB64, err := feeBumpTx.Base64()
+	assert.NoError(t, err)
+
+	// submission times out and the resubmission is included in a ledger
+	submissions := 0
+	hmock.On(
+		"POST",
+		"https://localhost/transactions",
+	).Return(func(request *http.Request) (*http.Response, error) {
+		submissions++
+		assert.Equal(t, feeBumpTxB64, request.FormValue("tx"))
+		if submissions < 2 {
+			return httpmock.NewStringResponse(http.StatusGatewayTimeout, timeoutResponse), nil
+		}
+		return httpmock.NewStringResponse(http.StatusOK, txSuccess), nil
+	})
+	hmock.On(
+		"GET",
+		"https://localhost/transactions/"+feeBumpTxHash,
+	).ReturnString(http.StatusNotFound, notFoundResponse)
+
+	resp, err = client.SubmitFeeBumpTransactionAndWait(context.Background(), feeBumpTx, network.TestNetworkPassphrase, opts)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int32(354811), resp.Ledger)
+	}
+	assert.Equal(t, 2, submissions)
+
+	// submission and resubmissions time out, transaction is pending and then
+	// included in a ledger
+	submissions = 0
+	hmock.On(
+		"POST",
+		"https://localhost/transactions",
+	).Return(func(request *http.Request) (*http.Response, error) {
+		submissions++
+		assert.Equal(t, feeBumpTxB64, request.FormValue("tx"))
+		return httpmock.NewStringResponse(http.StatusGatewayTimeout, timeoutResponse), nil
+	})
+
+	polls := 0
+	hmock.On(
+		"GET",
+		"https://localhost/transactions/"+feeBumpTxHash,
+	).Return(func(request *http.Request) (*http.Response, error) {
+		polls++
+		if polls < 3 {
+			return httpmock.NewStringResponse(http.StatusNotFound, notFoundResponse), nil
+		}
+		return httpmock.NewStringResponse(http.StatusOK, txSuccess), nil
+	})
+
+	resp, err = client.SubmitFeeBumpTransactionAndWait(context.Background(), feeBumpTx, network.TestNetworkPassphrase, opts)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int32(354811), resp.Ledger)
+	}
+	assert.Equal(t, 3, polls)
+	assert.Equal(t, 3, submissions)
+
+	// transaction is never included, waits until the context is done
+	hmock.On(
+		"GET",
+		"https://localhost/transactions/"+feeBumpTxHash,
+	).Return(func(request *http.Request) (*http.Response, error) {
+		return httpmock.NewStringResponse(http.StatusNotFound, notFoundResponse), nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.SubmitFeeBumpTransactionAndWait(ctx, feeBumpTx, network.TestNetworkPassphrase, opts)
+	if assert.Error(t, err) {
+		assert.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+	}
+
+	// submission fails with an error other than a timeout
+	hmock.On(
+		"POST",
+		"https://localhost/transactions",
+	).ReturnString(http.StatusBadRequest, transactionFailure)
+
+	_, err = client.SubmitFeeBumpTransactionAndWait(context.Background(), feeBumpTx, network.TestNetworkPassphrase, opts)
+	if assert.Error(t, err) {
+		hErr := GetError(err)
+		if assert.NotNil(t, hErr) {
+			assert.Equal(t, "Transaction Failed", hErr.Problem.Title)
+		}
+	}
+}
+
 func TestSubmitTransactionWithOptionsRequest(t *testing.T) {
 	hmock := httptest.NewClient()
 	client := &Client{
@@ -1661,6 +1805,13 @@ var memoRequiredResponse = map[string]string{
 	"value": "MQ==",
 }
 
+var timeoutResponse = `{
+  "type": "https://stellar.org/horizon-errors/timeout",
+  "title": "Timeout",
+  "status": 504,
+  "detail": "Your request timed out before completing.  Please try your request again. If you are submitting a transaction make sure you are sending exactly the same transaction (with the same sequence number)."
+}`
+
 var notFoundResponse = `{
   "type": "https://stellar.org/horizon-errors/not_found",
   "title": "Resource Missing",
@@ -115,6 +115,12 @@ func (m *MockClient) SubmitFeeBumpTransactionWithOptions(transaction *txnbuild.F
 	return a.Get(0).(hProtocol.Transaction), a.Error(1)
 }
 
+// SubmitFeeBumpTransactionAndWait is a mocking method
+func (m *MockClient) SubmitFeeBumpTransactionAndWait(ctx context.Context, transaction *txnbuild.FeeBumpTransaction, networkPassphrase string, opts SubmitTxOpts) (hProtocol.Transaction, error) {
+	a := m.Called(ctx, transaction, networkPassphrase, opts)
+	return a.Get(0).(hProtocol.Transaction), a.Error(1)
+}
+
 // SubmitTransactionWithOptions is a mocking method
 func (m *MockClient) SubmitTransactionWithOptions(transaction *txnbuild.Transaction, opts SubmitTxOpts) (hProtocol.Transaction, error) {
 	a := m.Called(transaction, opts)
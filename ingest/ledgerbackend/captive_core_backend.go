@@ -417,19 +417,18 @@ func (c *CaptiveStellarCore) isPrepared(ledgerRange Range) bool {
 		return false
 	}
 
-	if lastLedger == 0 {
-		return c.nextExpectedSequence() <= ledgerRange.from || cachedLedger == ledgerRange.from
+	// The cached ledger can still be returned even if it was already read.
+	from := c.nextExpectedSequence()
+	if cachedLedger == ledgerRange.from {
+		from = cachedLedger
 	}
 
-	// From now on: lastLedger != 0 so current range is bounded
-
-	if ledgerRange.bounded {
-		return (c.nextExpectedSequence() <= ledgerRange.from || cachedLedger == ledgerRange.from) &&
-			lastLedger >= ledgerRange.to
+	// The current range is unbounded if lastLedger == 0
+	current := UnboundedRange(from)
+	if lastLedger != 0 {
+		current = BoundedRange(from, lastLedger)
 	}
-
-	// Requested range is unbounded but current one is bounded
-	return false
+	return current.Contains(ledgerRange)
 }
 
 // GetLedger will block until the ledger is available in the backend
@@ -3,6 +3,8 @@ package ledgerbackend
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/pkg/errors"
 )
 
 // Range represents a range of ledger sequence numbers.
@@ -46,6 +48,21 @@ func (r Range) String() string {
 	return fmt.Sprintf("[%d,latest)", r.from)
 }
 
+// From returns the first ledger of the range.
+func (r Range) From() uint32 {
+	return r.from
+}
+
+// To returns the last ledger of a bounded range, 0 for an unbounded range.
+func (r Range) To() uint32 {
+	return r.to
+}
+
+// Bounded returns true if the range has a fixed ending ledger.
+func (r Range) Bounded() bool {
+	return r.bounded
+}
+
 func (r Range) Contains(other Range) bool {
 	if r.bounded && !other.bounded {
 		return false
@@ -56,6 +73,44 @@ func (r Range) Contains(other Range) bool {
 	return r.from <= other.from
 }
 
+// ContainsLedger returns true if the given ledger sequence is in the range.
+func (r Range) ContainsLedger(seq uint32) bool {
+	if seq < r.from {
+		return false
+	}
+	return !r.bounded || seq <= r.to
+}
+
+// Intersect returns the range of ledgers contained in both ranges. The returned
+// boolean is false if the ranges don't overlap.
+func (r Range) Intersect(other Range) (Range, bool) {
+	from := r.from
+	if other.from > from {
+		from = other.from
+	}
+
+	if !r.bounded && !other.bounded {
+		return UnboundedRange(from), true
+	}
+
+	var to uint32
+	switch {
+	case !r.bounded:
+		to = other.to
+	case !other.bounded:
+		to = r.to
+	case r.to < other.to:
+		to = r.to
+	default:
+		to = other.to
+	}
+
+	if from > to {
+		return Range{}, false
+	}
+	return BoundedRange(from, to), true
+}
+
 // SingleLedgerRange constructs a bounded range containing a single ledger.
 func SingleLedgerRange(ledger uint32) Range {
 	return Range{from: ledger, to: ledger, bounded: true}
@@ -70,3 +125,23 @@ func BoundedRange(from uint32, to uint32) Range {
 func UnboundedRange(from uint32) Range {
 	return Range{from: from, bounded: false}
 }
+
+// RangeFromBounds constructs a range of ledgers from start and end ledgers as
+// they are usually provided in flags: the range is unbounded if end is 0 and
+// bounded otherwise, containing a single ledger if end equals start. An error
+// is returned if start is 0 or if end is set but is less than start.
+func RangeFromBounds(start, end uint32) (Range, error) {
+	if start == 0 {
+		return Range{}, errors.New("start ledger must be greater than 0")
+	}
+	if end == 0 {
+		return UnboundedRange(start), nil
+	}
+	if end < start {
+		return Range{}, errors.Errorf("end ledger %d must not be less than start ledger %d", end, start)
+	}
+	if end == start {
+		return SingleLedgerRange(start), nil
+	}
+	return BoundedRange(start, end), nil
+}
@@ -0,0 +1,115 @@
+package ledgerbackend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeFromBounds(t *testing.T) {
+	for _, testCase := range []struct {
+		name          string
+		start         uint32
+		end           uint32
+		expected      Range
+		expectedError string
+	}{
+		{
+			name:     "unbounded when end is 0",
+			start:    2,
+			end:      0,
+			expected: UnboundedRange(2),
+		},
+		{
+			name:     "bounded when end is greater than start",
+			start:    2,
+			end:      3,
+			expected: BoundedRange(2, 3),
+		},
+		{
+			name:     "single ledger when end is equal to start",
+			start:    3,
+			end:      3,
+			expected: SingleLedgerRange(3),
+		},
+		{
+			name:          "end lower than start",
+			start:         3,
+			end:           2,
+			expectedError: "end ledger 2 must not be less than start ledger 3",
+		},
+		{
+			name:          "start is 0",
+			start:         0,
+			end:           10,
+			expectedError: "start ledger must be greater than 0",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			ledgerRange, err := RangeFromBounds(testCase.start, testCase.end)
+			if testCase.expectedError != "" {
+				require.EqualError(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, ledgerRange)
+			assert.Equal(t, testCase.start, ledgerRange.From())
+			assert.Equal(t, testCase.end, ledgerRange.To())
+			assert.Equal(t, testCase.end != 0, ledgerRange.Bounded())
+		})
+	}
+}
+
+func TestRangeContainsLedger(t *testing.T) {
+	for _, testCase := range []struct {
+		name     string
+		r        Range
+		seq      uint32
+		expected bool
+	}{
+		{"bounded before from", BoundedRange(2, 5), 1, false},
+		{"bounded at from", BoundedRange(2, 5), 2, true},
+		{"bounded at to", BoundedRange(2, 5), 5, true},
+		{"bounded after to", BoundedRange(2, 5), 6, false},
+		{"single ledger", SingleLedgerRange(3), 3, true},
+		{"unbounded before from", UnboundedRange(2), 1, false},
+		{"unbounded at from", UnboundedRange(2), 2, true},
+		{"unbounded far after from", UnboundedRange(2), 1000000, true},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, testCase.r.ContainsLedger(testCase.seq))
+		})
+	}
+}
+
+func TestRangeIntersect(t *testing.T) {
+	for _, testCase := range []struct {
+		name     string
+		a        Range
+		b        Range
+		expected Range
+		overlap  bool
+	}{
+		{"bounded overlapping", BoundedRange(2, 6), BoundedRange(4, 8), BoundedRange(4, 6), true},
+		{"bounded nested", BoundedRange(2, 8), BoundedRange(4, 6), BoundedRange(4, 6), true},
+		{"bounded touching", BoundedRange(2, 4), BoundedRange(4, 6), SingleLedgerRange(4), true},
+		{"bounded disjoint", BoundedRange(2, 3), BoundedRange(4, 6), Range{}, false},
+		{"bounded and unbounded overlapping", BoundedRange(2, 6), UnboundedRange(4), BoundedRange(4, 6), true},
+		{"bounded and unbounded nested", BoundedRange(4, 6), UnboundedRange(2), BoundedRange(4, 6), true},
+		{"bounded and unbounded disjoint", BoundedRange(2, 3), UnboundedRange(4), Range{}, false},
+		{"both unbounded", UnboundedRange(2), UnboundedRange(4), UnboundedRange(4), true},
+		{"both unbounded same start", UnboundedRange(3), UnboundedRange(3), UnboundedRange(3), true},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			intersection, ok := testCase.a.Intersect(testCase.b)
+			assert.Equal(t, testCase.overlap, ok)
+			assert.Equal(t, testCase.expected, intersection)
+
+			// the intersection is symmetric
+			intersection, ok = testCase.b.Intersect(testCase.a)
+			assert.Equal(t, testCase.overlap, ok)
+			assert.Equal(t, testCase.expected, intersection)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package ledgerbackend
+
+import (
+	"context"
+	"crypto/sha256"
+
+	"github.com/pkg/errors"
+
+	"github.com/stellar/go/xdr"
+)
+
+// VerifyRange reads all the ledgers of the given bounded range from the backend
+// and checks that they form a valid chain: every ledger must have the expected
+// sequence, its hash must match the hash of its header and its previous ledger
+// hash must match the hash of the ledger before it. It returns an error
+// describing the first corrupt ledger found.
+func VerifyRange(ctx context.Context, backend LedgerBackend, ledgerRange Range) error {
+	if !ledgerRange.bounded {
+		return errors.Errorf("cannot verify unbounded range %v", ledgerRange)
+	}
+	if ledgerRange.from == 0 || ledgerRange.to < ledgerRange.from {
+		return errors.Errorf("invalid range %v", ledgerRange)
+	}
+
+	if err := backend.PrepareRange(ctx, ledgerRange); err != nil {
+		return errors.Wrapf(err, "error preparing range %v", ledgerRange)
+	}
+
+	var previousHash xdr.Hash
+	for sequence := ledgerRange.from; sequence <= ledgerRange.to; sequence++ {
+		ledger, err := backend.GetLedger(ctx, sequence)
+		if err != nil {
+			return errors.Wrapf(err, "error getting ledger %d", sequence)
+		}
+
+		if ledger.LedgerSequence() != sequence {
+			return errors.Errorf(
+				"ledger %d has unexpected sequence %d",
+				sequence, ledger.LedgerSequence(),
+			)
+		}
+
+		header := ledger.MustV0().LedgerHeader.Header
+		headerBytes, err := header.MarshalBinary()
+		if err != nil {
+			return errors.Wrapf(err, "error marshaling header of ledger %d", sequence)
+		}
+		if hash := xdr.Hash(sha256.Sum256(headerBytes)); hash != ledger.LedgerHash() {
+			return errors.Errorf(
+				"ledger %d hash %s does not match its header hash %s",
+				sequence, ledger.LedgerHash().HexString(), hash.HexString(),
+			)
+		}
+
+		if sequence > ledgerRange.from && ledger.PreviousLedgerHash() != previousHash {
+			return errors.Errorf(
+				"ledger %d previous ledger hash %s does not match ledger %d hash %s",
+				sequence, ledger.PreviousLedgerHash().HexString(),
+				sequence-1, previousHash.HexString(),
+			)
+		}
+		previousHash = ledger.LedgerHash()
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package ledgerbackend
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/xdr"
+)
+
+// buildLedgerChain returns valid ledgers from..to, each one pointing to the
+// hash of the previous one.
+func buildLedgerChain(t *testing.T, from, to uint32) []xdr.LedgerCloseMeta {
+	var ledgers []xdr.LedgerCloseMeta
+	var previousHash xdr.Hash
+	for sequence := from; sequence <= to; sequence++ {
+		header := xdr.LedgerHeader{
+			LedgerSeq:          xdr.Uint32(sequence),
+			PreviousLedgerHash: previousHash,
+		}
+		headerBytes, err := header.MarshalBinary()
+		require.NoError(t, err)
+		hash := xdr.Hash(sha256.Sum256(headerBytes))
+
+		ledgers = append(ledgers, xdr.LedgerCloseMeta{
+			V0: &xdr.LedgerCloseMetaV0{
+				LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+					Hash:   hash,
+					Header: header,
+				},
+			},
+		})
+		previousHash = hash
+	}
+	return ledgers
+}
+
+func mockLedgerChain(ledgerRange Range, ledgers []xdr.LedgerCloseMeta) *MockDatabaseBackend {
+	backend := &MockDatabaseBackend{}
+	backend.On("PrepareRange", context.Background(), ledgerRange).Return(nil).Once()
+	for _, ledger := range ledgers {
+		backend.On("GetLedger", context.Background(), ledger.LedgerSequence()).
+			Return(ledger, nil).Once()
+	}
+	return backend
+}
+
+func TestVerifyRange(t *testing.T) {
+	ledgerRange := BoundedRange(2, 6)
+	backend := mockLedgerChain(ledgerRange, buildLedgerChain(t, 2, 6))
+
+	require.NoError(t, VerifyRange(context.Background(), backend, ledgerRange))
+	backend.AssertExpectations(t)
+}
+
+func TestVerifyRangeCorruptLedger(t *testing.T) {
+	ledgerRange := BoundedRange(2, 6)
+	ledgers := buildLedgerChain(t, 2, 6)
+	// the header of ledger 4 doesn't match its hash anymore
+	ledgers[2].V0.LedgerHeader.Header.TotalCoins = 100
+	backend := mockLedgerChain(ledgerRange, ledgers[:3])
+
+	err := VerifyRange(context.Background(), backend, ledgerRange)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ledger 4 hash")
+	assert.Contains(t, err.Error(), "does not match its header hash")
+	backend.AssertExpectations(t)
+}
+
+func TestVerifyRangeBrokenChain(t *testing.T) {
+	ledgerRange := BoundedRange(2, 6)
+	ledgers := buildLedgerChain(t, 2, 6)
+	// ledger 5 is valid by itself but belongs to a different chain
+	other := buildLedgerChain(t, 1, 5)
+	ledgers[3] = other[4]
+	backend := mockLedgerChain(ledgerRange, ledgers[:4])
+
+	err := VerifyRange(context.Background(), backend, ledgerRange)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ledger 5 previous ledger hash")
+	backend.AssertExpectations(t)
+}
+
+func TestVerifyRangeUnexpectedSequence(t *testing.T) {
+	ledgerRange := BoundedRange(2, 3)
+	ledgers := buildLedgerChain(t, 2, 3)
+	backend := &MockDatabaseBackend{}
+	backend.On("PrepareRange", context.Background(), ledgerRange).Return(nil).Once()
+	backend.On("GetLedger", context.Background(), uint32(2)).Return(ledgers[1], nil).Once()
+
+	err := VerifyRange(context.Background(), backend, ledgerRange)
+	assert.EqualError(t, err, "ledger 2 has unexpected sequence 3")
+	backend.AssertExpectations(t)
+}
+
+func TestVerifyRangeUnbounded(t *testing.T) {
+	backend := &MockDatabaseBackend{}
+	err := VerifyRange(context.Background(), backend, UnboundedRange(2))
+	assert.EqualError(t, err, "cannot verify unbounded range [2,latest)")
+	backend.AssertExpectations(t)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/stellar/go/ingest/ledgerbackend"
 	"github.com/stellar/go/services/horizon/internal/db2/history"
 
 	horizon "github.com/stellar/go/services/horizon/internal"
@@ -302,12 +303,17 @@ var dbReingestRangeCmd = &cobra.Command{
 			}
 		}
 
-		err := horizon.ApplyFlags(config, flags, horizon.ApplyOptions{RequireCaptiveCoreConfig: false, AlwaysIngest: true})
+		ledgerRange, err := boundedRangeFromArgs(argsUInt32[0], argsUInt32[1])
+		if err != nil {
+			return err
+		}
+
+		err = horizon.ApplyFlags(config, flags, horizon.ApplyOptions{RequireCaptiveCoreConfig: false, AlwaysIngest: true})
 		if err != nil {
 			return err
 		}
 		return runDBReingestRange(
-			[]history.LedgerRange{{StartSequence: argsUInt32[0], EndSequence: argsUInt32[1]}},
+			[]history.LedgerRange{{StartSequence: ledgerRange.From(), EndSequence: ledgerRange.To()}},
 			reingestForce,
 			parallelWorkers,
 			*config,
@@ -315,6 +321,19 @@ var dbReingestRangeCmd = &cobra.Command{
 	},
 }
 
+// boundedRangeFromArgs returns the range of ledgers between start and end
+// (inclusive) given to commands which can't run on an unbounded range.
+func boundedRangeFromArgs(start, end uint32) (ledgerbackend.Range, error) {
+	ledgerRange, err := ledgerbackend.RangeFromBounds(start, end)
+	if err != nil {
+		return ledgerbackend.Range{}, err
+	}
+	if !ledgerRange.Bounded() {
+		return ledgerbackend.Range{}, errors.New("end ledger must be greater than 0")
+	}
+	return ledgerRange, nil
+}
+
 var dbFillGapsCmdOpts = ingestRangeCmdOpts()
 var dbFillGapsCmd = &cobra.Command{
 	Use:   "fill-gaps [Start sequence number] [End sequence number]",
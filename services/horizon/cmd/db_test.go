@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stellar/go/ingest/ledgerbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoundedRangeFromArgs(t *testing.T) {
+	ledgerRange, err := boundedRangeFromArgs(2, 5)
+	require.NoError(t, err)
+	assert.Equal(t, ledgerbackend.BoundedRange(2, 5), ledgerRange)
+
+	ledgerRange, err = boundedRangeFromArgs(3, 3)
+	require.NoError(t, err)
+	assert.Equal(t, ledgerbackend.SingleLedgerRange(3), ledgerRange)
+
+	_, err = boundedRangeFromArgs(0, 5)
+	assert.EqualError(t, err, "start ledger must be greater than 0")
+
+	_, err = boundedRangeFromArgs(5, 2)
+	assert.EqualError(t, err, "end ledger 2 must not be less than start ledger 5")
+
+	_, err = boundedRangeFromArgs(5, 0)
+	assert.EqualError(t, err, "end ledger must be greater than 0")
+}
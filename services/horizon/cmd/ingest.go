@@ -73,6 +73,11 @@ var ingestVerifyRangeCmd = &cobra.Command{
 			co.SetValue()
 		}
 
+		ledgerRange, err := boundedRangeFromArgs(ingestVerifyFrom, ingestVerifyTo)
+		if err != nil {
+			return err
+		}
+
 		if err := horizon.ApplyFlags(config, flags, horizon.ApplyOptions{RequireCaptiveCoreConfig: false, AlwaysIngest: true}); err != nil {
 			return err
 		}
@@ -136,8 +141,8 @@ var ingestVerifyRangeCmd = &cobra.Command{
 		}
 
 		err = system.VerifyRange(
-			ingestVerifyFrom,
-			ingestVerifyTo,
+			ledgerRange.From(),
+			ledgerRange.To(),
 			ingestVerifyState,
 		)
 		if err != nil {
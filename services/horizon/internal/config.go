@@ -106,4 +106,11 @@ type Config struct {
 	BehindAWSLoadBalancer bool
 	// RoundingSlippageFilter excludes trades from /trade_aggregations with rounding slippage >x bps
 	RoundingSlippageFilter int
+	// TradeAggregationRebuildBatchSize is the maximum number of ledgers whose
+	// trade aggregation buckets are rebuilt at once while catching up
+	TradeAggregationRebuildBatchSize uint
+	// IngestRetryBackoffBase and IngestRetryBackoffMax bound the time slept
+	// between consecutive retries of ingesting a ledger
+	IngestRetryBackoffBase time.Duration
+	IngestRetryBackoffMax  time.Duration
 }
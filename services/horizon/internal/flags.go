@@ -545,6 +545,30 @@ func Flags() (*Config, support.ConfigOptions) {
 			Required:    false,
 			Usage:       "excludes trades from /trade_aggregations unless their rounding slippage is <x bps",
 		},
+		&support.ConfigOption{
+			Name:        "trade-aggregation-rebuild-batch-size",
+			ConfigKey:   &config.TradeAggregationRebuildBatchSize,
+			OptType:     types.Uint,
+			FlagDefault: uint(1),
+			Required:    false,
+			Usage:       "maximum number of ledgers whose trade aggregation buckets are rebuilt at once while ingestion is catching up, 1 rebuilds them after every ledger",
+		},
+		&support.ConfigOption{
+			Name:           "ingest-retry-backoff-base",
+			ConfigKey:      &config.IngestRetryBackoffBase,
+			OptType:        types.Int,
+			FlagDefault:    1,
+			CustomSetValue: support.SetDuration,
+			Usage:          "time slept before retrying to ingest a ledger after an error (in seconds), doubled on consecutive errors up to ingest-retry-backoff-max",
+		},
+		&support.ConfigOption{
+			Name:           "ingest-retry-backoff-max",
+			ConfigKey:      &config.IngestRetryBackoffMax,
+			OptType:        types.Int,
+			FlagDefault:    30,
+			CustomSetValue: support.SetDuration,
+			Usage:          "maximum time slept before retrying to ingest a ledger after consecutive errors (in seconds)",
+		},
 	}
 
 	return config, flags
@@ -740,6 +764,10 @@ func ApplyFlags(config *Config, flags support.ConfigOptions, options ApplyOption
 		config.HorizonDBMaxIdleConnections = config.MaxDBConnections
 	}
 
+	if config.IngestRetryBackoffMax < config.IngestRetryBackoffBase {
+		return fmt.Errorf("Invalid config: --ingest-retry-backoff-max cannot be lower than --ingest-retry-backoff-base")
+	}
+
 	if config.BehindCloudflare && config.BehindAWSLoadBalancer {
 		return fmt.Errorf("Invalid config: Only one option of --behind-cloudflare and --behind-aws-load-balancer is allowed. If Horizon is behind both, use --behind-cloudflare only.")
 	}
@@ -3,6 +3,7 @@ package ingest
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"strings"
 	"time"
 
@@ -18,8 +19,16 @@ import (
 
 var (
 	defaultSleep = time.Second
+	// defaultRetryBackoffMax is the maximum time slept between consecutive
+	// retries of resumeState when none is configured.
+	defaultRetryBackoffMax = 30 * time.Second
 )
 
+// tradeAggregationCatchupThreshold is the age of a ledger above which ingestion
+// is considered to be catching up, so the rebuild of its trade aggregation
+// buckets can be batched with the following ledgers.
+const tradeAggregationCatchupThreshold = time.Minute
+
 // ErrReingestRangeConflict indicates that the reingest range overlaps with
 // horizon's most recently ingested ledger
 type ErrReingestRangeConflict struct {
@@ -33,6 +42,28 @@ func (e ErrReingestRangeConflict) Error() string {
 type stateMachineNode interface {
 	run(*system) (transition, error)
 	String() string
+	// name returns the name of the state without its parameters, e.g.
+	// "resume" for resumeState.
+	name() string
+}
+
+// stateLedger returns the ledger the state starts from, or 0 if it doesn't
+// start from a ledger.
+func stateLedger(node stateMachineNode) uint32 {
+	switch state := node.(type) {
+	case resumeState:
+		return state.latestSuccessfullyProcessedLedger
+	case buildState:
+		return state.checkpointLedger
+	case historyRangeState:
+		return state.fromLedger
+	case reingestHistoryRangeState:
+		return state.fromLedger
+	case verifyRangeState:
+		return state.fromLedger
+	default:
+		return 0
+	}
 }
 
 type transition struct {
@@ -75,10 +106,33 @@ func resumeImmediately(latestSuccessfullyProcessedLedger uint32) transition {
 	}
 }
 
-func retryResume(r resumeState) transition {
+// retryResume returns a transition retrying r. The first retry sleeps for the
+// configured base backoff, consecutive ones back off exponentially, with
+// jitter, up to the configured max backoff.
+func (s *system) retryResume(r resumeState) transition {
+	base, maxBackoff := s.config.RetryBackoffBase, s.config.RetryBackoffMax
+	if base <= 0 {
+		base = defaultSleep
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryBackoffMax
+	}
+
+	sleep := base
+	if s.consecutiveResumeRetries > 0 {
+		for i := 0; i < s.consecutiveResumeRetries && sleep < maxBackoff; i++ {
+			sleep *= 2
+		}
+		if sleep > maxBackoff {
+			sleep = maxBackoff
+		}
+		sleep = sleep/2 + time.Duration(rand.Int63n(int64(sleep/2)+1))
+	}
+	s.consecutiveResumeRetries++
+
 	return transition{
 		node:          r,
-		sleepDuration: defaultSleep,
+		sleepDuration: sleep,
 	}
 }
 
@@ -105,6 +159,10 @@ func (stopState) String() string {
 	return "stop"
 }
 
+func (stopState) name() string {
+	return "stop"
+}
+
 func (stopState) run(s *system) (transition, error) {
 	return stop(), errors.New("Cannot run terminal state")
 }
@@ -117,6 +175,10 @@ func (startState) String() string {
 	return "start"
 }
 
+func (startState) name() string {
+	return "start"
+}
+
 func (state startState) run(s *system) (transition, error) {
 	if err := s.historyQ.Begin(); err != nil {
 		return start(), errors.Wrap(err, "Error starting a transaction")
@@ -233,6 +295,10 @@ func (b buildState) String() string {
 	return fmt.Sprintf("buildFromCheckpoint(checkpointLedger=%d)", b.checkpointLedger)
 }
 
+func (buildState) name() string {
+	return "buildFromCheckpoint"
+}
+
 func (b buildState) run(s *system) (transition, error) {
 	var nextFailState = start()
 	if b.stop {
@@ -369,7 +435,18 @@ func (r resumeState) String() string {
 	return fmt.Sprintf("resume(latestSuccessfullyProcessedLedger=%d)", r.latestSuccessfullyProcessedLedger)
 }
 
-func (r resumeState) run(s *system) (transition, error) {
+func (resumeState) name() string {
+	return "resume"
+}
+
+func (r resumeState) run(s *system) (next transition, err error) {
+	defer func() {
+		// retries are only returned along with an error, so they are over
+		if err == nil {
+			s.consecutiveResumeRetries = 0
+		}
+	}()
+
 	if r.latestSuccessfullyProcessedLedger == 0 {
 		return start(), errors.New("unexpected latestSuccessfullyProcessedLedger value")
 	}
@@ -378,7 +455,7 @@ func (r resumeState) run(s *system) (transition, error) {
 
 	ingestLedger := r.latestSuccessfullyProcessedLedger + 1
 
-	err := s.maybePrepareRange(s.ctx, ingestLedger)
+	err = s.maybePrepareRange(s.ctx, ingestLedger)
 	if err != nil {
 		return start(), err
 	}
@@ -398,7 +475,7 @@ func (r resumeState) run(s *system) (transition, error) {
 	s.Metrics().LedgerFetchDurationSummary.Observe(float64(duration))
 
 	if err = s.historyQ.Begin(); err != nil {
-		return retryResume(r),
+		return s.retryResume(r),
 			errors.Wrap(err, "Error starting a transaction")
 	}
 	defer s.historyQ.Rollback()
@@ -406,7 +483,7 @@ func (r resumeState) run(s *system) (transition, error) {
 	// This will get the value `FOR UPDATE`, blocking it for other nodes.
 	lastIngestedLedger, err := s.historyQ.GetLastLedgerIngest(s.ctx)
 	if err != nil {
-		return retryResume(r), errors.Wrap(err, getLastIngestedErrMsg)
+		return s.retryResume(r), errors.Wrap(err, getLastIngestedErrMsg)
 	}
 
 	if ingestLedger > lastIngestedLedger+1 {
@@ -432,7 +509,7 @@ func (r resumeState) run(s *system) (transition, error) {
 
 	ingestVersion, err := s.historyQ.GetIngestVersion(s.ctx)
 	if err != nil {
-		return retryResume(r), errors.Wrap(err, getIngestVersionErrMsg)
+		return s.retryResume(r), errors.Wrap(err, getIngestVersionErrMsg)
 	}
 
 	if ingestVersion != CurrentVersion {
@@ -445,7 +522,7 @@ func (r resumeState) run(s *system) (transition, error) {
 
 	lastHistoryLedger, err := s.historyQ.GetLatestHistoryLedger(s.ctx)
 	if err != nil {
-		return retryResume(r), errors.Wrap(err, "could not get latest history ledger")
+		return s.retryResume(r), errors.Wrap(err, "could not get latest history ledger")
 	}
 
 	if lastHistoryLedger != 0 && lastHistoryLedger != lastIngestedLedger {
@@ -471,20 +548,29 @@ func (r resumeState) run(s *system) (transition, error) {
 	stats, err :=
 		s.runner.RunAllProcessorsOnLedger(ledgerCloseMeta)
 	if err != nil {
-		return retryResume(r), errors.Wrap(err, "Error running processors on ledger")
+		return s.retryResume(r), errors.Wrap(err, "Error running processors on ledger")
 	}
 
-	rebuildStart := time.Now()
-	err = s.historyQ.RebuildTradeAggregationBuckets(s.ctx, ingestLedger, ingestLedger, s.config.RoundingSlippageFilter)
-	if err != nil {
-		return stop(), errors.Wrap(err, "error rebuilding trade aggregations")
+	rebuildFrom, rebuild := s.tradeAggregationRebuildRange(ingestLedger, ledgerCloseMeta)
+	if rebuild {
+		rebuildStart := time.Now()
+		err = s.historyQ.RebuildTradeAggregationBuckets(s.ctx, rebuildFrom, ingestLedger, s.config.RoundingSlippageFilter)
+		if err != nil {
+			return stop(), errors.Wrap(err, "error rebuilding trade aggregations")
+		}
+		rebuildDuration := time.Since(rebuildStart).Seconds()
+		s.Metrics().LedgerIngestionTradeAggregationDuration.Observe(float64(rebuildDuration))
 	}
-	rebuildDuration := time.Since(rebuildStart).Seconds()
-	s.Metrics().LedgerIngestionTradeAggregationDuration.Observe(float64(rebuildDuration))
 
 	if err = s.completeIngestion(s.ctx, ingestLedger); err != nil {
-		return retryResume(r), err
+		return s.retryResume(r), err
+	}
+	if rebuild {
+		s.tradeAggregationRebuildFrom = ingestLedger + 1
+	} else {
+		s.tradeAggregationRebuildFrom = rebuildFrom
 	}
+	s.Metrics().LastIngestedLedgerTimestamp.Set(float64(s.clock.Now().Unix()))
 
 	if err = s.updateCursor(ingestLedger); err != nil {
 		// Don't return updateCursor error.
@@ -527,6 +613,32 @@ func (r resumeState) run(s *system) (transition, error) {
 	return resumeImmediately(ingestLedger), nil
 }
 
+// tradeAggregationRebuildRange returns the first ledger whose trade aggregation
+// buckets need to be rebuilt along with the ones of ingestLedger, and whether
+// they should be rebuilt now. While catching up, the rebuild is coalesced over
+// up to TradeAggregationRebuildBatchSize ledgers.
+func (s *system) tradeAggregationRebuildRange(ingestLedger uint32, ledgerCloseMeta xdr.LedgerCloseMeta) (uint32, bool) {
+	batchSize := s.config.TradeAggregationRebuildBatchSize
+	if batchSize <= 1 {
+		return ingestLedger, true
+	}
+
+	from := s.tradeAggregationRebuildFrom
+	if from == 0 || from > ingestLedger || ingestLedger-from >= batchSize {
+		// The buckets of the ledgers ingested before this one may not have
+		// been rebuilt, e.g. if Horizon was restarted in the middle of a
+		// batch, so rebuild a whole batch.
+		from = 1
+		if ingestLedger > batchSize {
+			from = ingestLedger - batchSize + 1
+		}
+	}
+
+	closeTime := time.Unix(int64(ledgerCloseMeta.MustV0().LedgerHeader.Header.ScpValue.CloseTime), 0)
+	catchingUp := s.clock.Now().Sub(closeTime) > tradeAggregationCatchupThreshold
+	return from, !catchingUp || ingestLedger-from+1 >= batchSize
+}
+
 func (r resumeState) addLedgerStatsMetricFromMap(s *system, prefix string, m map[string]interface{}) {
 	for stat, value := range m {
 		stat = strings.Replace(stat, "stats_", prefix+"_", 1)
@@ -559,6 +671,10 @@ func (h historyRangeState) String() string {
 	)
 }
 
+func (historyRangeState) name() string {
+	return "historyRange"
+}
+
 // historyRangeState is used when catching up history data
 func (h historyRangeState) run(s *system) (transition, error) {
 	if h.fromLedger == 0 || h.toLedger == 0 ||
@@ -672,6 +788,10 @@ func (h reingestHistoryRangeState) String() string {
 	)
 }
 
+func (reingestHistoryRangeState) name() string {
+	return "reingestHistoryRange"
+}
+
 func (h reingestHistoryRangeState) ingestRange(s *system, fromLedger, toLedger uint32) error {
 	if s.historyQ.GetTx() == nil {
 		return errors.New("expected transaction to be present")
@@ -826,6 +946,10 @@ func (waitForCheckpointState) String() string {
 	return "waitForCheckpoint"
 }
 
+func (waitForCheckpointState) name() string {
+	return "waitForCheckpoint"
+}
+
 func (waitForCheckpointState) run(*system) (transition, error) {
 	log.Info("Waiting for the next checkpoint...")
 	time.Sleep(10 * time.Second)
@@ -847,6 +971,10 @@ func (v verifyRangeState) String() string {
 	)
 }
 
+func (verifyRangeState) name() string {
+	return "verifyRange"
+}
+
 func (v verifyRangeState) run(s *system) (transition, error) {
 	if v.fromLedger == 0 || v.toLedger == 0 ||
 		v.fromLedger > v.toLedger {
@@ -977,6 +1105,10 @@ func (stressTestState) String() string {
 	return "stressTest"
 }
 
+func (stressTestState) name() string {
+	return "stressTest"
+}
+
 func (stressTestState) run(s *system) (transition, error) {
 	if err := s.historyQ.Begin(); err != nil {
 		err = errors.Wrap(err, "Error starting a transaction")
@@ -20,6 +20,7 @@ import (
 	"github.com/stellar/go/services/horizon/internal/db2/history"
 	"github.com/stellar/go/services/horizon/internal/ingest/filters"
 	apkg "github.com/stellar/go/support/app"
+	"github.com/stellar/go/support/clock"
 	"github.com/stellar/go/support/db"
 	"github.com/stellar/go/support/errors"
 	logpkg "github.com/stellar/go/support/log"
@@ -96,7 +97,24 @@ type Config struct {
 
 	RoundingSlippageFilter int
 
+	// TradeAggregationRebuildBatchSize is the maximum number of ledgers whose
+	// trade aggregation buckets are rebuilt at once while catching up. When 0
+	// or 1 the buckets are rebuilt after every ledger.
+	TradeAggregationRebuildBatchSize uint32
+
+	// RetryBackoffBase and RetryBackoffMax bound the time slept before
+	// consecutive retries of ingesting a ledger, which grows exponentially
+	// from the base to the max. They default to 1 and 30 seconds.
+	RetryBackoffBase time.Duration
+	RetryBackoffMax  time.Duration
+
 	EnableIngestionFiltering bool
+
+	// StateTransitionHook, when set, is called on every transition of the
+	// ingestion state machine with the names of the states, e.g. "resume", and
+	// the ledger the next state starts from (0 if it doesn't start from one).
+	// It is called from the state machine goroutine and must not block.
+	StateTransitionHook func(from, to string, ledger uint32)
 }
 
 const (
@@ -119,6 +137,10 @@ type Metrics struct {
 	// LocalLedger exposes the last ingested ledger by this ingesting instance.
 	LocalLatestLedger prometheus.Gauge
 
+	// LastIngestedLedgerTimestamp exposes the unix time at which the last
+	// ledger was ingested by this ingesting instance.
+	LastIngestedLedgerTimestamp prometheus.Gauge
+
 	// LedgerIngestionDuration exposes timing metrics about the rate and
 	// duration of ledger ingestion (including updating DB and graph).
 	LedgerIngestionDuration prometheus.Summary
@@ -201,6 +223,17 @@ type system struct {
 	disableStateVerification bool
 
 	checkpointManager historyarchive.CheckpointManager
+
+	clock clock.Clock
+
+	// tradeAggregationRebuildFrom is the first ledger ingested by resumeState
+	// whose trade aggregation buckets haven't been rebuilt yet, or 0 if it
+	// isn't known, e.g. after a restart.
+	tradeAggregationRebuildFrom uint32
+
+	// consecutiveResumeRetries is the number of times resumeState was retried
+	// since it last ran without error.
+	consecutiveResumeRetries int
 }
 
 func NewSystem(config Config) (System, error) {
@@ -303,6 +336,11 @@ func (s *system) initMetrics() {
 		Help: "sequence number of the latest ledger ingested by this ingesting instance",
 	})
 
+	s.metrics.LastIngestedLedgerTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "horizon", Subsystem: "ingest", Name: "last_ingested_ledger_timestamp",
+		Help: "unix timestamp of the latest ledger ingested by this ingesting instance",
+	})
+
 	s.metrics.LedgerIngestionDuration = prometheus.NewSummary(prometheus.SummaryOpts{
 		Namespace: "horizon", Subsystem: "ingest", Name: "ledger_ingestion_duration_seconds",
 		Help: "ledger ingestion durations, sliding window = 10m",
@@ -443,6 +481,7 @@ func (s *system) Metrics() Metrics {
 func (s *system) RegisterMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(s.metrics.MaxSupportedProtocolVersion)
 	registry.MustRegister(s.metrics.LocalLatestLedger)
+	registry.MustRegister(s.metrics.LastIngestedLedgerTimestamp)
 	registry.MustRegister(s.metrics.LedgerIngestionDuration)
 	registry.MustRegister(s.metrics.LedgerIngestionTradeAggregationDuration)
 	registry.MustRegister(s.metrics.StateVerifyDuration)
@@ -606,6 +645,7 @@ func (s *system) runStateMachine(cur stateMachineNode) error {
 
 		// Exit after processing shutdownState
 		if next.node == (stopState{}) {
+			s.notifyStateTransition(cur, next.node)
 			log.Info("Shut down")
 			return err
 		}
@@ -621,10 +661,19 @@ func (s *system) runStateMachine(cur stateMachineNode) error {
 			"current_state": cur,
 			"next_state":    next.node,
 		}).Info("Ingestion system state machine transition")
+		s.notifyStateTransition(cur, next.node)
 		cur = next.node
 	}
 }
 
+// notifyStateTransition calls the configured StateTransitionHook, if any.
+func (s *system) notifyStateTransition(from, to stateMachineNode) {
+	if s.config.StateTransitionHook == nil {
+		return
+	}
+	s.config.StateTransitionHook(from.name(), to.name(), stateLedger(to))
+}
+
 func (s *system) maybeVerifyState(lastIngestedLedger uint32) {
 	stateInvalid, err := s.historyQ.GetExpStateInvalid(s.ctx)
 	if err != nil && !isCancelledError(err) {
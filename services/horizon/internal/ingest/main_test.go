@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/prometheus/client_golang/prometheus"
@@ -162,6 +163,75 @@ func TestStateMachineRunReturnsErrorWhenNextStateIsShutdownWithError(t *testing.
 	assert.EqualError(t, err, "invalid range: [0, 0]")
 }
 
+func TestStateMachineTransitionHook(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	historyQ := &mockDBQ{}
+	ledgerBackend := &ledgerbackend.MockDatabaseBackend{}
+	type hookCall struct {
+		from, to string
+		ledger   uint32
+	}
+	var calls []hookCall
+	system := &system{
+		ctx:           ctx,
+		historyQ:      historyQ,
+		ledgerBackend: ledgerBackend,
+		config: Config{
+			RetryBackoffBase: time.Millisecond,
+			StateTransitionHook: func(from, to string, ledger uint32) {
+				calls = append(calls, hookCall{from, to, ledger})
+				// shut down while the retried state runs
+				cancel()
+			},
+		},
+	}
+	system.initMetrics()
+
+	historyQ.On("GetTx").Return(nil).Twice()
+
+	// the first run fails to start a transaction so resumeState is retried
+	ledgerBackend.On("IsPrepared", ctx, ledgerbackend.UnboundedRange(101)).Return(false, nil).Once()
+	ledgerBackend.On("PrepareRange", ctx, ledgerbackend.UnboundedRange(101)).Return(nil).Once()
+	ledgerBackend.On("GetLedger", ctx, uint32(101)).Return(xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{LedgerSeq: 101},
+			},
+		},
+	}, nil).Once()
+	historyQ.On("Begin").Return(errors.New("my error")).Once()
+
+	// the retry is interrupted by the shutdown
+	ledgerBackend.On("IsPrepared", ctx, ledgerbackend.UnboundedRange(101)).Return(true, nil).Once()
+	ledgerBackend.On("GetLedger", ctx, uint32(101)).Return(xdr.LedgerCloseMeta{}, context.Canceled).Once()
+
+	assert.NoError(t, system.runStateMachine(resumeState{latestSuccessfullyProcessedLedger: 100}))
+	assert.Equal(t, []hookCall{{"resume", "resume", 100}}, calls)
+	historyQ.AssertExpectations(t)
+	ledgerBackend.AssertExpectations(t)
+}
+
+func TestStateNameAndLedger(t *testing.T) {
+	for _, tc := range []struct {
+		node   stateMachineNode
+		name   string
+		ledger uint32
+	}{
+		{startState{}, "start", 0},
+		{resumeState{latestSuccessfullyProcessedLedger: 100}, "resume", 100},
+		{buildState{checkpointLedger: 63}, "buildFromCheckpoint", 63},
+		{historyRangeState{fromLedger: 2, toLedger: 10}, "historyRange", 2},
+		{reingestHistoryRangeState{fromLedger: 3, toLedger: 10}, "reingestHistoryRange", 3},
+		{verifyRangeState{fromLedger: 4, toLedger: 10}, "verifyRange", 4},
+		{waitForCheckpointState{}, "waitForCheckpoint", 0},
+		{stopState{}, "stop", 0},
+	} {
+		assert.Equal(t, tc.name, tc.node.name())
+		assert.Equal(t, tc.ledger, stateLedger(tc.node))
+	}
+}
+
 func TestMaybeVerifyStateGetExpStateInvalidDBErrCancelOrContextCanceled(t *testing.T) {
 	historyQ := &mockDBQ{}
 	system := &system{
@@ -5,12 +5,16 @@ package ingest
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/stellar/go/historyarchive"
 	"github.com/stellar/go/ingest/ledgerbackend"
+	"github.com/stellar/go/support/clock"
+	"github.com/stellar/go/support/clock/clocktest"
 	"github.com/stellar/go/support/errors"
 	"github.com/stellar/go/xdr"
 )
@@ -314,6 +318,9 @@ func (s *ResumeTestTestSuite) TestBumpIngestLedger() {
 	// Skips state verification but ensures maybeVerifyState called
 	s.historyQ.On("GetExpStateInvalid", s.ctx).Return(true, nil).Once()
 
+	// bumping the ledger also resets the retry backoff
+	s.system.consecutiveResumeRetries = 3
+
 	next, err := resumeState{latestSuccessfullyProcessedLedger: 99}.run(s.system)
 	s.Assert().NoError(err)
 	s.Assert().Equal(
@@ -323,6 +330,7 @@ func (s *ResumeTestTestSuite) TestBumpIngestLedger() {
 		},
 		next,
 	)
+	s.Assert().Zero(s.system.consecutiveResumeRetries)
 }
 
 func (s *ResumeTestTestSuite) TestIngestAllMasterNode() {
@@ -339,6 +347,141 @@ func (s *ResumeTestTestSuite) TestIngestAllMasterNode() {
 	)
 }
 
+func (s *ResumeTestTestSuite) TestLastIngestedLedgerTimestamp() {
+	s.mockSuccessfulIngestion()
+
+	commitTime := time.Unix(1600000000, 0)
+	s.system.clock = clock.Clock{Source: clocktest.FixedSource(commitTime)}
+	_, err := resumeState{latestSuccessfullyProcessedLedger: 100}.run(s.system)
+	s.Assert().NoError(err)
+
+	// the gauge keeps the last commit time as the clock advances without
+	// ingesting ledgers
+	s.system.clock = clock.Clock{Source: clocktest.FixedSource(commitTime.Add(time.Minute))}
+	s.Assert().Equal(float64(commitTime.Unix()), testutil.ToFloat64(s.system.Metrics().LastIngestedLedgerTimestamp))
+}
+
+func (s *ResumeTestTestSuite) mockLedgerIngestion(sequence uint32, closeTime time.Time) {
+	s.ledgerBackend.On("IsPrepared", s.ctx, ledgerbackend.UnboundedRange(sequence)).Return(true, nil).Once()
+	s.ledgerBackend.On("GetLedger", s.ctx, sequence).Return(xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					LedgerSeq:      xdr.Uint32(sequence),
+					LedgerVersion:  xdr.Uint32(MaxSupportedProtocolVersion),
+					BucketListHash: xdr.Hash{1, 2, 3},
+					ScpValue:       xdr.StellarValue{CloseTime: xdr.TimePoint(closeTime.Unix())},
+				},
+			},
+		},
+	}, nil).Once()
+
+	s.historyQ.On("Begin").Return(nil).Once()
+	s.historyQ.On("Rollback").Return(nil).Once()
+	s.historyQ.On("GetLastLedgerIngest", s.ctx).Return(sequence-1, nil).Once()
+	s.historyQ.On("GetIngestVersion", s.ctx).Return(CurrentVersion, nil).Once()
+	s.historyQ.On("GetLatestHistoryLedger", s.ctx).Return(sequence-1, nil).Once()
+	s.runner.On("RunAllProcessorsOnLedger", mock.AnythingOfType("xdr.LedgerCloseMeta")).
+		Return(ledgerStats{}, nil).Once()
+	s.historyQ.On("UpdateLastLedgerIngest", s.ctx, sequence).Return(nil).Once()
+	s.historyQ.On("Commit").Return(nil).Once()
+	s.stellarCoreClient.On(
+		"SetCursor",
+		mock.AnythingOfType("*context.timerCtx"),
+		defaultCoreCursorName,
+		int32(sequence),
+	).Return(nil).Once()
+	s.historyQ.On("GetExpStateInvalid", s.ctx).Return(false, nil).Once()
+}
+
+func (s *ResumeTestTestSuite) TestTradeAggregationRebuildCoalesced() {
+	*s.historyQ = mockDBQ{}
+	*s.ledgerBackend = ledgerbackend.MockDatabaseBackend{}
+	s.system.config.TradeAggregationRebuildBatchSize = 3
+	// the buckets of the ledgers up to 100 were rebuilt
+	s.system.tradeAggregationRebuildFrom = 101
+
+	closeTime := time.Unix(1600000000, 0)
+	s.system.clock = clock.Clock{Source: clocktest.FixedSource(closeTime.Add(time.Hour))}
+	for sequence := uint32(101); sequence <= 103; sequence++ {
+		s.mockLedgerIngestion(sequence, closeTime)
+	}
+	// catching up, the three ledgers are rebuilt at once
+	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(101), uint32(103), 0).Return(nil).Once()
+
+	state := stateMachineNode(resumeState{latestSuccessfullyProcessedLedger: 100})
+	for i := 0; i < 3; i++ {
+		next, err := state.run(s.system)
+		s.Assert().NoError(err)
+		state = next.node
+	}
+	s.Assert().Equal(resumeState{latestSuccessfullyProcessedLedger: 103}, state)
+	s.Assert().Equal(uint32(104), s.system.tradeAggregationRebuildFrom)
+}
+
+func (s *ResumeTestTestSuite) TestTradeAggregationRebuildAfterRestart() {
+	*s.historyQ = mockDBQ{}
+	*s.ledgerBackend = ledgerbackend.MockDatabaseBackend{}
+	s.system.config.TradeAggregationRebuildBatchSize = 3
+
+	closeTime := time.Unix(1600000000, 0)
+	s.system.clock = clock.Clock{Source: clocktest.FixedSource(closeTime.Add(time.Hour))}
+	s.mockLedgerIngestion(101, closeTime)
+	// the ledgers ingested before the restart may not have been rebuilt
+	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(99), uint32(101), 0).Return(nil).Once()
+
+	_, err := resumeState{latestSuccessfullyProcessedLedger: 100}.run(s.system)
+	s.Assert().NoError(err)
+	s.Assert().Equal(uint32(102), s.system.tradeAggregationRebuildFrom)
+}
+
+func (s *ResumeTestTestSuite) TestTradeAggregationRebuildNotCatchingUp() {
+	*s.historyQ = mockDBQ{}
+	*s.ledgerBackend = ledgerbackend.MockDatabaseBackend{}
+	s.system.config.TradeAggregationRebuildBatchSize = 3
+	s.system.tradeAggregationRebuildFrom = 101
+
+	closeTime := time.Unix(1600000000, 0)
+	s.system.clock = clock.Clock{Source: clocktest.FixedSource(closeTime.Add(5 * time.Second))}
+	s.mockLedgerIngestion(101, closeTime)
+	// recent ledgers are rebuilt right away
+	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(101), uint32(101), 0).Return(nil).Once()
+
+	_, err := resumeState{latestSuccessfullyProcessedLedger: 100}.run(s.system)
+	s.Assert().NoError(err)
+	s.Assert().Equal(uint32(102), s.system.tradeAggregationRebuildFrom)
+}
+
+func (s *ResumeTestTestSuite) TestRetryBackoff() {
+	s.system.config.RetryBackoffBase = time.Second
+	s.system.config.RetryBackoffMax = 10 * time.Second
+	r := resumeState{latestSuccessfullyProcessedLedger: 100}
+
+	// the first retry sleeps for the base backoff
+	s.Assert().Equal(time.Second, s.system.retryResume(r).sleepDuration)
+
+	// consecutive retries back off exponentially with jitter up to the max
+	for _, maxSleep := range []time.Duration{
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		10 * time.Second,
+		10 * time.Second,
+	} {
+		next := s.system.retryResume(r)
+		s.Assert().Equal(r, next.node)
+		s.Assert().GreaterOrEqual(next.sleepDuration, maxSleep/2)
+		s.Assert().LessOrEqual(next.sleepDuration, maxSleep)
+	}
+
+	// a successfully ingested ledger resets the backoff
+	s.mockSuccessfulIngestion()
+	next, err := resumeState{latestSuccessfullyProcessedLedger: 100}.run(s.system)
+	s.Assert().NoError(err)
+	s.Assert().Equal(time.Duration(0), next.sleepDuration)
+	s.Assert().Equal(time.Second, s.system.retryResume(r).sleepDuration)
+}
+
 func (s *ResumeTestTestSuite) TestErrorSettingCursorIgnored() {
 	s.historyQ.On("Begin").Return(nil).Once()
 	s.historyQ.On("GetLastLedgerIngest", s.ctx).Return(uint32(100), nil).Once()
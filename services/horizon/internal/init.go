@@ -27,7 +27,7 @@ func mustNewDBSession(subservice db.Subservice, databaseURL string, maxIdle, max
 
 	session.DB.SetMaxIdleConns(maxIdle)
 	session.DB.SetMaxOpenConns(maxOpen)
-	return db.RegisterMetrics(session, "horizon", subservice, registry)
+	return db.RegisterMetrics(session, "horizon", subservice, registry, db.MetricsOptions{})
 }
 
 func mustInitHorizonDB(app *App) {
@@ -105,20 +105,23 @@ func initIngester(app *App) {
 		// TODO:
 		// Use the first archive for now. We don't have a mechanism to
 		// use multiple archives at the same time currently.
-		HistoryArchiveURL:            app.config.HistoryArchiveURLs[0],
-		CheckpointFrequency:          app.config.CheckpointFrequency,
-		StellarCoreURL:               app.config.StellarCoreURL,
-		StellarCoreCursor:            app.config.CursorName,
-		CaptiveCoreBinaryPath:        app.config.CaptiveCoreBinaryPath,
-		CaptiveCoreStoragePath:       app.config.CaptiveCoreStoragePath,
-		CaptiveCoreConfigUseDB:       app.config.CaptiveCoreConfigUseDB,
-		CaptiveCoreToml:              app.config.CaptiveCoreToml,
-		RemoteCaptiveCoreURL:         app.config.RemoteCaptiveCoreURL,
-		EnableCaptiveCore:            app.config.EnableCaptiveCoreIngestion,
-		DisableStateVerification:     app.config.IngestDisableStateVerification,
-		EnableExtendedLogLedgerStats: app.config.IngestEnableExtendedLogLedgerStats,
-		RoundingSlippageFilter:       app.config.RoundingSlippageFilter,
-		EnableIngestionFiltering:     app.config.EnableIngestionFiltering,
+		HistoryArchiveURL:                app.config.HistoryArchiveURLs[0],
+		CheckpointFrequency:              app.config.CheckpointFrequency,
+		StellarCoreURL:                   app.config.StellarCoreURL,
+		StellarCoreCursor:                app.config.CursorName,
+		CaptiveCoreBinaryPath:            app.config.CaptiveCoreBinaryPath,
+		CaptiveCoreStoragePath:           app.config.CaptiveCoreStoragePath,
+		CaptiveCoreConfigUseDB:           app.config.CaptiveCoreConfigUseDB,
+		CaptiveCoreToml:                  app.config.CaptiveCoreToml,
+		RemoteCaptiveCoreURL:             app.config.RemoteCaptiveCoreURL,
+		EnableCaptiveCore:                app.config.EnableCaptiveCoreIngestion,
+		DisableStateVerification:         app.config.IngestDisableStateVerification,
+		EnableExtendedLogLedgerStats:     app.config.IngestEnableExtendedLogLedgerStats,
+		RoundingSlippageFilter:           app.config.RoundingSlippageFilter,
+		TradeAggregationRebuildBatchSize: uint32(app.config.TradeAggregationRebuildBatchSize),
+		RetryBackoffBase:                 app.config.IngestRetryBackoffBase,
+		RetryBackoffMax:                  app.config.IngestRetryBackoffMax,
+		EnableIngestionFiltering:         app.config.EnableIngestionFiltering,
 	})
 
 	if err != nil {
@@ -84,6 +84,62 @@ func (c *ServeCommand) Command() *cobra.Command {
 			FlagDefault: "500",
 			Required:    true,
 		},
+		{
+			Name:        "review-required-payment-amount-threshold",
+			Usage:       "The amount threshold above which additional information is requested, must be lower than the KYC threshold, disabled when empty",
+			OptType:     types.String,
+			ConfigKey:   &opts.ReviewRequiredPaymentAmountThreshold,
+			FlagDefault: "",
+			Required:    false,
+		},
+		{
+			Name:        "max-operations",
+			Usage:       "The maximum number of operations a transaction may have to be inspected for approval, must be at least 5 to allow compliant transactions",
+			OptType:     types.Int,
+			ConfigKey:   &opts.MaxOperations,
+			FlagDefault: 10,
+			Required:    true,
+		},
+		{
+			Name:        "max-timeout",
+			Usage:       "The maximum number of seconds a transaction max time can be in the future to be approved, unbounded when 0",
+			OptType:     types.Int,
+			ConfigKey:   &opts.MaxTimeout,
+			FlagDefault: 0,
+			Required:    false,
+		},
+		{
+			Name:        "require-min-time",
+			Usage:       "Whether transactions must have a min time to be approved",
+			OptType:     types.Bool,
+			ConfigKey:   &opts.RequireMinTime,
+			FlagDefault: false,
+			Required:    false,
+		},
+		{
+			Name:        "rate-limit",
+			Usage:       "The maximum number of transactions per minute each source account can submit for approval, unlimited when 0",
+			OptType:     types.Int,
+			ConfigKey:   &opts.RateLimit,
+			FlagDefault: 0,
+			Required:    false,
+		},
+		{
+			Name:        "idempotency-ttl",
+			Usage:       "The number of seconds a resubmitted transaction gets the same signed transaction, disabled when 0",
+			OptType:     types.Int,
+			ConfigKey:   &opts.IdempotencyTTL,
+			FlagDefault: 0,
+			Required:    false,
+		},
+		{
+			Name:        "admin-port",
+			Usage:       "Port to listen and serve admin functionality including metrics, disabled when 0",
+			OptType:     types.Int,
+			ConfigKey:   &opts.AdminPort,
+			FlagDefault: 0,
+			Required:    false,
+		},
 	}
 	cmd := &cobra.Command{
 		Use:   "serve",
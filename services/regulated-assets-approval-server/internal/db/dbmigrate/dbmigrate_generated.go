@@ -3,6 +3,7 @@
 // migrations/2021-05-05.0.initial.sql (162B)
 // migrations/2021-05-18.0.accounts-kyc-status.sql (414B)
 // migrations/2021-06-08.0.pending-kyc-status.sql (193B)
+// migrations/2021-06-15.0.kyc-full-name.sql (171B)
 
 package dbmigrate
 
@@ -131,6 +132,26 @@ func migrations202106080PendingKycStatusSql() (*asset, error) {
 	return a, nil
 }
 
+var _migrations202106150KycFullNameSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x55\xd0\xce\xcd\x4c\x2f\x4a\x2c\x49\x55\x08\x2d\xe0\xe2\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x28\x4d\xca\xc9\x4c\xd6\x4b\x4c\x4e\xce\x2f\xcd\x2b\x29\x8e\xcf\xae\x4c\x8e\x2f\x2e\x49\x2c\x29\x2d\xe6\x52\x50\x50\x50\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\x48\x2b\xcd\xc9\x89\xcf\x4b\xcc\x4d\x55\x28\x49\xad\x28\xb1\xe6\xe2\x42\x36\xd6\x25\xbf\x3c\x8f\x24\x83\x5d\x82\xfc\x03\x30\x4c\xb6\xe6\x02\x0c\x00\x99\xa2\x69\x20\xab\x00\x00\x00")
+
+func migrations202106150KycFullNameSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202106150KycFullNameSql,
+		"migrations/2021-06-15.0.kyc-full-name.sql",
+	)
+}
+
+func migrations202106150KycFullNameSql() (*asset, error) {
+	bytes, err := migrations202106150KycFullNameSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/2021-06-15.0.kyc-full-name.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x50, 0x48, 0xd9, 0x18, 0x27, 0x3c, 0xab, 0x39, 0x44, 0x18, 0x2b, 0x24, 0x98, 0x58, 0x9c, 0xdb, 0x4c, 0x79, 0xfb, 0x55, 0xf6, 0x5e, 0x25, 0x75, 0xa0, 0x5f, 0xbf, 0x77, 0x5a, 0x40, 0x4, 0x7b}}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -225,6 +246,7 @@ var _bindata = map[string]func() (*asset, error){
 	"migrations/2021-05-05.0.initial.sql":             migrations202105050InitialSql,
 	"migrations/2021-05-18.0.accounts-kyc-status.sql": migrations202105180AccountsKycStatusSql,
 	"migrations/2021-06-08.0.pending-kyc-status.sql":  migrations202106080PendingKycStatusSql,
+	"migrations/2021-06-15.0.kyc-full-name.sql":       migrations202106150KycFullNameSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -272,6 +294,7 @@ var _bintree = &bintree{nil, map[string]*bintree{
 		"2021-05-05.0.initial.sql":             &bintree{migrations202105050InitialSql, map[string]*bintree{}},
 		"2021-05-18.0.accounts-kyc-status.sql": &bintree{migrations202105180AccountsKycStatusSql, map[string]*bintree{}},
 		"2021-06-08.0.pending-kyc-status.sql":  &bintree{migrations202106080PendingKycStatusSql, map[string]*bintree{}},
+		"2021-06-15.0.kyc-full-name.sql":       &bintree{migrations202106150KycFullNameSql, map[string]*bintree{}},
 	}},
 }}
 
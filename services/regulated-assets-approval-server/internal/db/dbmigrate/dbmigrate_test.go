@@ -50,6 +50,7 @@ func TestPlanMigration_upApplyAll(t *testing.T) {
 		"2021-05-05.0.initial.sql",
 		"2021-05-18.0.accounts-kyc-status.sql",
 		"2021-06-08.0.pending-kyc-status.sql",
+		"2021-06-15.0.kyc-full-name.sql",
 	}
 	assert.Equal(t, wantAtLeastMigrations, migrations)
 }
@@ -154,6 +155,7 @@ func TestMigrate_upApplyAll(t *testing.T) {
 		"2021-05-05.0.initial.sql",
 		"2021-05-18.0.accounts-kyc-status.sql",
 		"2021-06-08.0.pending-kyc-status.sql",
+		"2021-06-15.0.kyc-full-name.sql",
 	}
 	assert.Equal(t, wantIDs, ids)
 }
@@ -178,6 +180,7 @@ func TestMigrate_upApplyNone(t *testing.T) {
 		"2021-05-05.0.initial.sql",
 		"2021-05-18.0.accounts-kyc-status.sql",
 		"2021-06-08.0.pending-kyc-status.sql",
+		"2021-06-15.0.kyc-full-name.sql",
 	}
 	assert.Equal(t, wantIDs, ids)
 }
@@ -0,0 +1,9 @@
+-- +migrate Up
+
+ALTER TABLE public.accounts_kyc_status
+    ADD COLUMN full_name text;
+
+-- +migrate Down
+
+ALTER TABLE public.accounts_kyc_status
+    DROP COLUMN full_name;
@@ -88,6 +88,19 @@ func TestAPI_txApprove_revised(t *testing.T) {
 			AccountID: senderKP.Address(),
 			Sequence:  5,
 		}, nil)
+	horizonMock.
+		On("Assets", horizonclient.AssetRequest{
+			ForAssetCode:   assetGOAT.GetCode(),
+			ForAssetIssuer: issuerKP.Address(),
+			Limit:          1,
+		}).
+		Return(horizon.AssetsPage{
+			Embedded: struct{ Records []horizon.AssetStat }{
+				Records: []horizon.AssetStat{
+					{Flags: horizon.AccountFlags{AuthRequired: true, AuthRevocable: true}},
+				},
+			},
+		}, nil)
 
 	handler := txApproveHandler{
 		issuerKP:          issuerKP,
@@ -295,6 +308,19 @@ func TestAPI_txAprove_actionRequiredFlow(t *testing.T) {
 			AccountID: senderKP.Address(),
 			Sequence:  1,
 		}, nil)
+	horizonMock.
+		On("Assets", horizonclient.AssetRequest{
+			ForAssetCode:   assetGOAT.GetCode(),
+			ForAssetIssuer: issuerKP.Address(),
+			Limit:          1,
+		}).
+		Return(horizon.AssetsPage{
+			Embedded: struct{ Records []horizon.AssetStat }{
+				Records: []horizon.AssetStat{
+					{Flags: horizon.AccountFlags{AuthRequired: true, AuthRevocable: true}},
+				},
+			},
+		}, nil)
 
 	handler := txApproveHandler{
 		issuerKP:          issuerKP,
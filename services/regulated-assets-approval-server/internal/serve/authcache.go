@@ -0,0 +1,45 @@
+package serve
+
+import (
+	"sync"
+	"time"
+)
+
+// authRequiredCacheTTL is how long the authorization flag of the regulated
+// asset is kept before being looked up in Horizon again.
+const authRequiredCacheTTL = time.Minute
+
+// authRequiredCache keeps whether the regulated asset requires authorization
+// for a short while, as the issuer flags rarely change and they would otherwise
+// be looked up for every approval request.
+type authRequiredCache struct {
+	mu           sync.Mutex
+	ttl          time.Duration
+	authRequired bool
+	expiresAt    time.Time
+}
+
+func newAuthRequiredCache(ttl time.Duration) *authRequiredCache {
+	return &authRequiredCache{ttl: ttl}
+}
+
+// get returns the cached authorization flag, and false if there is none or it
+// has expired at now.
+func (c *authRequiredCache) get(now time.Time) (authRequired bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !now.Before(c.expiresAt) {
+		return false, false
+	}
+	return c.authRequired, true
+}
+
+// set caches the authorization flag from now until the cache ttl elapses.
+func (c *authRequiredCache) set(authRequired bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.authRequired = authRequired
+	c.expiresAt = now.Add(c.ttl)
+}
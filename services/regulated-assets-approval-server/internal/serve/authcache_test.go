@@ -0,0 +1,30 @@
+package serve
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthRequiredCache(t *testing.T) {
+	cache := newAuthRequiredCache(time.Minute)
+	now := time.Unix(1600000000, 0)
+
+	_, ok := cache.get(now)
+	require.False(t, ok)
+
+	cache.set(false, now)
+	authRequired, ok := cache.get(now.Add(59 * time.Second))
+	require.True(t, ok)
+	require.False(t, authRequired)
+
+	// the flag expires after the ttl
+	_, ok = cache.get(now.Add(time.Minute))
+	require.False(t, ok)
+
+	cache.set(true, now.Add(time.Minute))
+	authRequired, ok = cache.get(now.Add(time.Minute))
+	require.True(t, ok)
+	require.True(t, authRequired)
+}
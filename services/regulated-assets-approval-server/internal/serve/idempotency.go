@@ -0,0 +1,78 @@
+package serve
+
+import (
+	"sync"
+	"time"
+)
+
+// maxCachedTxApprovals is the maximum number of responses kept by the cache,
+// above which the oldest ones are dropped even if they haven't expired yet.
+const maxCachedTxApprovals = 10000
+
+type cachedTxApproval struct {
+	response  *txApprovalResponse
+	expiresAt time.Time
+}
+
+type queuedTxApproval struct {
+	txHash    string
+	expiresAt time.Time
+}
+
+// txApprovalCache keeps the responses given to transactions for a short while,
+// so resubmitting the same transaction returns the same decision instead of
+// being evaluated and signed again.
+type txApprovalCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]cachedTxApproval
+	// queue holds the cached hashes in insertion order, which is also their
+	// expiration order since they all share the same ttl.
+	queue []queuedTxApproval
+}
+
+func newTxApprovalCache(ttl time.Duration) *txApprovalCache {
+	return &txApprovalCache{
+		ttl:        ttl,
+		maxEntries: maxCachedTxApprovals,
+		entries:    map[string]cachedTxApproval{},
+	}
+}
+
+// get returns the response cached for the transaction hash, or nil if there is
+// none or it has expired at now.
+func (c *txApprovalCache) get(txHash string, now time.Time) *txApprovalResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[txHash]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil
+	}
+	return entry.response
+}
+
+// add caches the response of the transaction hash from now until the cache ttl
+// elapses, removing the expired responses and the oldest ones if the cache is
+// full.
+func (c *txApprovalCache) add(txHash string, response *txApprovalResponse, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := now.Add(c.ttl)
+	c.entries[txHash] = cachedTxApproval{
+		response:  response,
+		expiresAt: expiresAt,
+	}
+	c.queue = append(c.queue, queuedTxApproval{txHash: txHash, expiresAt: expiresAt})
+
+	for len(c.queue) > 0 && (len(c.queue) > c.maxEntries || !now.Before(c.queue[0].expiresAt)) {
+		oldest := c.queue[0]
+		c.queue = c.queue[1:]
+		// the hash may have been cached again since it was queued
+		if entry, ok := c.entries[oldest.txHash]; ok && entry.expiresAt.Equal(oldest.expiresAt) {
+			delete(c.entries, oldest.txHash)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package serve
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxApprovalCache(t *testing.T) {
+	cache := newTxApprovalCache(time.Minute)
+	now := time.Unix(1600000000, 0)
+	resp := NewSuccessTxApprovalResponse("AAAA", "Transaction is compliant and signed by the issuer.")
+
+	require.Nil(t, cache.get("hash-a", now))
+
+	cache.add("hash-a", resp, now)
+	require.Equal(t, resp, cache.get("hash-a", now))
+	require.Equal(t, resp, cache.get("hash-a", now.Add(59*time.Second)))
+	require.Nil(t, cache.get("hash-b", now))
+
+	// responses expire after the ttl
+	require.Nil(t, cache.get("hash-a", now.Add(time.Minute)))
+
+	// expired responses are removed when adding new ones
+	cache.add("hash-b", resp, now.Add(time.Minute))
+	require.Len(t, cache.entries, 1)
+	require.Equal(t, resp, cache.get("hash-b", now.Add(time.Minute)))
+}
+
+func TestTxApprovalCache_maxEntries(t *testing.T) {
+	cache := newTxApprovalCache(time.Minute)
+	cache.maxEntries = 2
+	now := time.Unix(1600000000, 0)
+	resp := NewSuccessTxApprovalResponse("AAAA", "Transaction is compliant and signed by the issuer.")
+
+	cache.add("hash-a", resp, now)
+	cache.add("hash-b", resp, now.Add(time.Second))
+	cache.add("hash-c", resp, now.Add(2*time.Second))
+
+	// the oldest response is dropped although it hasn't expired
+	require.Len(t, cache.entries, 2)
+	require.Len(t, cache.queue, 2)
+	require.Nil(t, cache.get("hash-a", now.Add(2*time.Second)))
+	require.Equal(t, resp, cache.get("hash-b", now.Add(2*time.Second)))
+	require.Equal(t, resp, cache.get("hash-c", now.Add(2*time.Second)))
+}
+
+func TestTxApprovalCache_addAgain(t *testing.T) {
+	cache := newTxApprovalCache(time.Minute)
+	now := time.Unix(1600000000, 0)
+	resp := NewSuccessTxApprovalResponse("AAAA", "Transaction is compliant and signed by the issuer.")
+
+	cache.add("hash-a", resp, now)
+	cache.add("hash-a", resp, now.Add(30*time.Second))
+
+	// the first expiration doesn't remove the response cached again
+	cache.add("hash-b", resp, now.Add(time.Minute))
+	require.Equal(t, resp, cache.get("hash-a", now.Add(time.Minute)))
+	require.Len(t, cache.queue, 2)
+}
@@ -18,13 +18,25 @@ type kycGetResponse struct {
 	StellarAddress string     `json:"stellar_address"`
 	CallbackID     string     `json:"callback_id"`
 	EmailAddress   string     `json:"email_address,omitempty"`
+	FullName       string     `json:"full_name,omitempty"`
 	CreatedAt      *time.Time `json:"created_at"`
 	KYCSubmittedAt *time.Time `json:"kyc_submitted_at,omitempty"`
 	ApprovedAt     *time.Time `json:"approved_at,omitempty"`
 	RejectedAt     *time.Time `json:"rejected_at,omitempty"`
 	PendingAt      *time.Time `json:"pending_at,omitempty"`
+	Status         kycStatus  `json:"status,omitempty"`
 }
 
+// kycStatus is the KYC decision taken for an account, allowing wallets to poll
+// for it before resubmitting a transaction to /tx-approve.
+type kycStatus string
+
+const (
+	kycStatusApproved kycStatus = "approved"
+	kycStatusRejected kycStatus = "rejected"
+	kycStatusPending  kycStatus = "pending"
+)
+
 func (k *kycGetResponse) Render(w http.ResponseWriter) {
 	httpjson.Render(w, k, httpjson.JSON)
 }
@@ -83,16 +95,16 @@ func (h GetDetailHandler) handle(ctx context.Context, in getDetailRequest) (*kyc
 	// Prepare SELECT query return values.
 	var (
 		stellarAddress, callbackID                        string
-		emailAddress                                      sql.NullString
+		emailAddress, fullName                            sql.NullString
 		createdAt                                         time.Time
 		kycSubmittedAt, approvedAt, rejectedAt, pendingAt sql.NullTime
 	)
 	const q = `
-		SELECT stellar_address, email_address, created_at, kyc_submitted_at, approved_at, rejected_at, pending_at, callback_id
+		SELECT stellar_address, email_address, full_name, created_at, kyc_submitted_at, approved_at, rejected_at, pending_at, callback_id
 		FROM accounts_kyc_status
 		WHERE stellar_address = $1 OR callback_id = $1
 	`
-	err := h.DB.QueryRowContext(ctx, q, in.StellarAddressOrCallbackID).Scan(&stellarAddress, &emailAddress, &createdAt, &kycSubmittedAt, &approvedAt, &rejectedAt, &pendingAt, &callbackID)
+	err := h.DB.QueryRowContext(ctx, q, in.StellarAddressOrCallbackID).Scan(&stellarAddress, &emailAddress, &fullName, &createdAt, &kycSubmittedAt, &approvedAt, &rejectedAt, &pendingAt, &callbackID)
 	if err == sql.ErrNoRows {
 		return nil, httperror.NewHTTPError(http.StatusNotFound, "Not found.")
 	}
@@ -104,14 +116,31 @@ func (h GetDetailHandler) handle(ctx context.Context, in getDetailRequest) (*kyc
 		StellarAddress: stellarAddress,
 		CallbackID:     callbackID,
 		EmailAddress:   emailAddress.String,
+		FullName:       fullName.String,
 		CreatedAt:      &createdAt,
 		KYCSubmittedAt: timePointerIfValid(kycSubmittedAt),
 		ApprovedAt:     timePointerIfValid(approvedAt),
 		RejectedAt:     timePointerIfValid(rejectedAt),
 		PendingAt:      timePointerIfValid(pendingAt),
+		Status:         kycStatusFromDates(approvedAt, rejectedAt, pendingAt),
 	}, nil
 }
 
+// kycStatusFromDates returns the KYC decision recorded for an account, or an
+// empty status if no KYC was submitted yet.
+func kycStatusFromDates(approvedAt, rejectedAt, pendingAt sql.NullTime) kycStatus {
+	switch {
+	case approvedAt.Valid:
+		return kycStatusApproved
+	case rejectedAt.Valid:
+		return kycStatusRejected
+	case pendingAt.Valid:
+		return kycStatusPending
+	default:
+		return ""
+	}
+}
+
 // timePointerIfValid returns a pointer to the date from the provided
 // `sql.NullTime` if it's valid or `nil` if it's not.
 func timePointerIfValid(nt sql.NullTime) *time.Time {
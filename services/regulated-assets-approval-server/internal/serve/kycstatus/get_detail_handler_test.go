@@ -45,6 +45,14 @@ func TestTimePointerIfValid(t *testing.T) {
 	require.Equal(t, &desiredTime, timePointer)
 }
 
+func TestKYCStatusFromDates(t *testing.T) {
+	validTime := sql.NullTime{Time: time.Now(), Valid: true}
+	assert.Equal(t, kycStatus(""), kycStatusFromDates(sql.NullTime{}, sql.NullTime{}, sql.NullTime{}))
+	assert.Equal(t, kycStatusApproved, kycStatusFromDates(validTime, sql.NullTime{}, sql.NullTime{}))
+	assert.Equal(t, kycStatusRejected, kycStatusFromDates(sql.NullTime{}, validTime, sql.NullTime{}))
+	assert.Equal(t, kycStatusPending, kycStatusFromDates(sql.NullTime{}, sql.NullTime{}, validTime))
+}
+
 func TestGetDetailHandler_handle_error(t *testing.T) {
 	db := dbtest.Open(t)
 	defer db.Close()
@@ -104,6 +112,7 @@ func TestGetDetailHandler_handle_success(t *testing.T) {
 		RejectedAt:     &rejectedAt,
 		PendingAt:      nil,
 		ApprovedAt:     nil,
+		Status:         kycStatusRejected,
 	}
 	assert.Equal(t, &wantKYCGetResponse, kycGetResp)
 
@@ -126,6 +135,7 @@ func TestGetDetailHandler_handle_success(t *testing.T) {
 		RejectedAt:     nil,
 		PendingAt:      &pendingAt,
 		ApprovedAt:     nil,
+		Status:         kycStatusPending,
 	}
 	assert.Equal(t, &wantKYCGetResponse, kycGetResp)
 
@@ -148,6 +158,7 @@ func TestGetDetailHandler_handle_success(t *testing.T) {
 		RejectedAt:     nil,
 		PendingAt:      nil,
 		ApprovedAt:     &approvedAt,
+		Status:         kycStatusApproved,
 	}
 	assert.Equal(t, &wantKYCGetResponse, kycGetResp)
 
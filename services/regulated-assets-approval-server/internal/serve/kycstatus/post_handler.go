@@ -18,6 +18,7 @@ import (
 type kycPostRequest struct {
 	CallbackID   string `path:"callback_id"`
 	EmailAddress string `json:"email_address"`
+	FullName     string `json:"full_name"`
 }
 
 type kycPostResponse struct {
@@ -84,10 +85,10 @@ func (h PostHandler) handle(ctx context.Context, in kycPostRequest) (*kycPostRes
 	if in.CallbackID == "" {
 		return nil, httperror.NewHTTPError(http.StatusBadRequest, "Missing callbackID.")
 	}
-	if in.EmailAddress == "" {
-		return nil, httperror.NewHTTPError(http.StatusBadRequest, "Missing email_address.")
+	if in.EmailAddress == "" && in.FullName == "" {
+		return nil, httperror.NewHTTPError(http.StatusBadRequest, "Missing email_address or full_name.")
 	}
-	if !RxEmail.MatchString(in.EmailAddress) {
+	if in.EmailAddress != "" && !RxEmail.MatchString(in.EmailAddress) {
 		return nil, httperror.NewHTTPError(http.StatusBadRequest, "The provided email_address is invalid.")
 	}
 
@@ -117,6 +118,9 @@ func (in kycPostRequest) isKYCPending() bool {
 }
 
 // buildUpdateKYCQuery builds a query that will approve or reject stellar account from accounts_kyc_status table.
+// The full name, the additional information requested for payments in the
+// review band, is stored if provided. If only the full name was provided the
+// query marks the KYC as submitted without taking any decision.
 // Afterwards the query should return an exists boolean if present.
 func (in kycPostRequest) buildUpdateKYCQuery() (string, []interface{}) {
 	var (
@@ -125,18 +129,28 @@ func (in kycPostRequest) buildUpdateKYCQuery() (string, []interface{}) {
 	)
 	query.WriteString("WITH updated_row AS (")
 	query.WriteString("UPDATE accounts_kyc_status ")
-	query.WriteString("SET kyc_submitted_at = NOW(), ")
+	query.WriteString("SET kyc_submitted_at = NOW()")
 
-	args = append(args, in.EmailAddress)
-	query.WriteString(fmt.Sprintf("email_address = $%d, ", len(args)))
+	if in.FullName != "" {
+		args = append(args, in.FullName)
+		query.WriteString(fmt.Sprintf(", full_name = $%d", len(args)))
+	}
 
-	// update KYC status to rejected, pending or approved
-	if in.isKYCRejected() {
-		query.WriteString("rejected_at = NOW(), pending_at = NULL, approved_at = NULL ")
-	} else if in.isKYCPending() {
-		query.WriteString("rejected_at = NULL, pending_at = NOW(), approved_at = NULL ")
+	// if only additional information was provided no KYC decision is made
+	if in.EmailAddress == "" {
+		query.WriteString(" ")
 	} else {
-		query.WriteString("rejected_at = NULL, pending_at = NULL, approved_at = NOW() ")
+		args = append(args, in.EmailAddress)
+		query.WriteString(fmt.Sprintf(", email_address = $%d, ", len(args)))
+
+		// update KYC status to rejected, pending or approved
+		if in.isKYCRejected() {
+			query.WriteString("rejected_at = NOW(), pending_at = NULL, approved_at = NULL ")
+		} else if in.isKYCPending() {
+			query.WriteString("rejected_at = NULL, pending_at = NOW(), approved_at = NULL ")
+		} else {
+			query.WriteString("rejected_at = NULL, pending_at = NULL, approved_at = COALESCE(approved_at, NOW()) ")
+		}
 	}
 
 	args = append(args, in.CallbackID)
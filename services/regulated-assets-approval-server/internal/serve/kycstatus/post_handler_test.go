@@ -89,10 +89,33 @@ func TestBuildUpdateKYCQuery(t *testing.T) {
 		EmailAddress: "test@email.com",
 	}
 	query, args = in.buildUpdateKYCQuery()
-	expectedQuery = "WITH updated_row AS (UPDATE accounts_kyc_status SET kyc_submitted_at = NOW(), email_address = $1, rejected_at = NULL, pending_at = NULL, approved_at = NOW() WHERE callback_id = $2 RETURNING * )\n\t\tSELECT EXISTS(\n\t\t\tSELECT * FROM updated_row\n\t\t)\n\t"
+	expectedQuery = "WITH updated_row AS (UPDATE accounts_kyc_status SET kyc_submitted_at = NOW(), email_address = $1, rejected_at = NULL, pending_at = NULL, approved_at = COALESCE(approved_at, NOW()) WHERE callback_id = $2 RETURNING * )\n\t\tSELECT EXISTS(\n\t\t\tSELECT * FROM updated_row\n\t\t)\n\t"
 	expectedArgs = []interface{}{in.EmailAddress, in.CallbackID}
 	require.Equal(t, expectedQuery, query)
 	require.Equal(t, expectedArgs, args)
+
+	// test additional information only query
+	in = kycPostRequest{
+		CallbackID: "1234567890-12345",
+		FullName:   "Jane Doe",
+	}
+	query, args = in.buildUpdateKYCQuery()
+	expectedQuery = "WITH updated_row AS (UPDATE accounts_kyc_status SET kyc_submitted_at = NOW(), full_name = $1 WHERE callback_id = $2 RETURNING * )\n\t\tSELECT EXISTS(\n\t\t\tSELECT * FROM updated_row\n\t\t)\n\t"
+	expectedArgs = []interface{}{in.FullName, in.CallbackID}
+	require.Equal(t, expectedQuery, query)
+	require.Equal(t, expectedArgs, args)
+
+	// test full name and email query
+	in = kycPostRequest{
+		CallbackID:   "1234567890-12345",
+		EmailAddress: "test@email.com",
+		FullName:     "Jane Doe",
+	}
+	query, args = in.buildUpdateKYCQuery()
+	expectedQuery = "WITH updated_row AS (UPDATE accounts_kyc_status SET kyc_submitted_at = NOW(), full_name = $1, email_address = $2, rejected_at = NULL, pending_at = NULL, approved_at = COALESCE(approved_at, NOW()) WHERE callback_id = $3 RETURNING * )\n\t\tSELECT EXISTS(\n\t\t\tSELECT * FROM updated_row\n\t\t)\n\t"
+	expectedArgs = []interface{}{in.FullName, in.EmailAddress, in.CallbackID}
+	require.Equal(t, expectedQuery, query)
+	require.Equal(t, expectedArgs, args)
 }
 
 func TestPostHandler_handle_error(t *testing.T) {
@@ -110,13 +133,13 @@ func TestPostHandler_handle_error(t *testing.T) {
 	require.Nil(t, kycPostResp)
 	require.Equal(t, httperror.NewHTTPError(http.StatusBadRequest, "Missing callbackID."), err)
 
-	// missing email_address
+	// missing email_address and full_name
 	in = kycPostRequest{
 		CallbackID: "random-callback-id",
 	}
 	kycPostResp, err = handler.handle(ctx, in)
 	require.Nil(t, kycPostResp)
-	require.Equal(t, httperror.NewHTTPError(http.StatusBadRequest, "Missing email_address."), err)
+	require.Equal(t, httperror.NewHTTPError(http.StatusBadRequest, "Missing email_address or full_name."), err)
 
 	// invalid email_address
 	in = kycPostRequest{
@@ -213,6 +236,75 @@ func TestPostHandler_handle_success(t *testing.T) {
 	assert.False(t, rejectedAt.Valid)
 	assert.False(t, pendingAt.Valid)
 	require.True(t, approvedAt.Valid)
+
+	// approving an account more than once is idempotent and keeps the date of
+	// the first approval
+	in = kycPostRequest{
+		CallbackID:   approvedCallbackID,
+		EmailAddress: "email@test.com",
+	}
+	var firstApprovedAt sql.NullTime
+	for i := 0; i < 2; i++ {
+		kycPostResp, err = handler.handle(ctx, in)
+		assert.NoError(t, err)
+		require.Equal(t, NewKYCStatusPostResponse(), kycPostResp)
+
+		err = conn.DB.QueryRowContext(ctx, q, approvedCallbackID).Scan(&rejectedAt, &pendingAt, &approvedAt)
+		require.NoError(t, err)
+
+		assert.False(t, rejectedAt.Valid)
+		assert.False(t, pendingAt.Valid)
+		require.True(t, approvedAt.Valid)
+		if i == 0 {
+			firstApprovedAt = approvedAt
+		}
+		assert.Equal(t, firstApprovedAt, approvedAt)
+	}
+}
+
+func TestPostHandler_handle_fullName(t *testing.T) {
+	db := dbtest.Open(t)
+	defer db.Close()
+	conn := db.Open()
+	defer conn.Close()
+	ctx := context.Background()
+
+	handler := PostHandler{DB: conn}
+
+	callbackID := "full-name-callback-id"
+	q := `
+		INSERT INTO accounts_kyc_status (stellar_address, callback_id)
+		VALUES ('full-name-address', $1)
+	`
+	_, err := conn.DB.ExecContext(ctx, q, callbackID)
+	require.NoError(t, err)
+
+	// the full name is stored and marks the KYC as submitted without a decision
+	in := kycPostRequest{
+		CallbackID: callbackID,
+		FullName:   "Jane Doe",
+	}
+	kycPostResp, err := handler.handle(ctx, in)
+	assert.NoError(t, err)
+	require.Equal(t, NewKYCStatusPostResponse(), kycPostResp)
+
+	var (
+		fullName                                       sql.NullString
+		submittedAt, rejectedAt, pendingAt, approvedAt sql.NullTime
+	)
+	q = `
+		SELECT full_name, kyc_submitted_at, rejected_at, pending_at, approved_at
+		FROM accounts_kyc_status
+		WHERE callback_id = $1
+	`
+	err = conn.DB.QueryRowContext(ctx, q, callbackID).Scan(&fullName, &submittedAt, &rejectedAt, &pendingAt, &approvedAt)
+	require.NoError(t, err)
+
+	assert.Equal(t, sql.NullString{String: "Jane Doe", Valid: true}, fullName)
+	assert.True(t, submittedAt.Valid)
+	assert.False(t, rejectedAt.Valid)
+	assert.False(t, pendingAt.Valid)
+	assert.False(t, approvedAt.Valid)
 }
 
 func TestRxEmail(t *testing.T) {
@@ -0,0 +1,57 @@
+package serve
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stellar/go/support/errors"
+)
+
+// txApproveMetrics holds the metrics reported by the txApproveHandler, counting
+// the decisions taken and measuring how long each decision took.
+type txApproveMetrics struct {
+	decisionsCounter  *prometheus.CounterVec
+	durationHistogram prometheus.Histogram
+}
+
+func newTxApproveMetrics(registry prometheus.Registerer) (*txApproveMetrics, error) {
+	m := &txApproveMetrics{
+		decisionsCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "sep8", Subsystem: "tx_approve", Name: "decisions_total",
+				Help: "Number of transaction approval decisions, labeled by the SEP-8 status returned.",
+			},
+			[]string{"status"},
+		),
+		durationHistogram: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace: "sep8", Subsystem: "tx_approve", Name: "duration_seconds",
+				Help:    "Time taken to decide on a transaction approval request.",
+				Buckets: prometheus.DefBuckets,
+			},
+		),
+	}
+
+	for _, collector := range []prometheus.Collector{m.decisionsCounter, m.durationHistogram} {
+		err := registry.Register(collector)
+		if err != nil {
+			return nil, errors.Wrap(err, "registering tx approve metrics")
+		}
+	}
+
+	return m, nil
+}
+
+// observe records the outcome of a single transaction approval request. An
+// empty status means no decision was reached, in which case only the duration
+// is recorded. It is safe to call on a nil receiver.
+func (m *txApproveMetrics) observe(status sep8Status, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.durationHistogram.Observe(duration.Seconds())
+	if status != "" {
+		m.decisionsCounter.With(prometheus.Labels{"status": string(status)}).Inc()
+	}
+}
@@ -0,0 +1,89 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func histogramSampleCount(t *testing.T, registry *prometheus.Registry) uint64 {
+	metricFamilies, err := registry.Gather()
+	require.NoError(t, err)
+	for _, mf := range metricFamilies {
+		if mf.GetName() == "sep8_tx_approve_duration_seconds" {
+			require.Len(t, mf.GetMetric(), 1)
+			return mf.GetMetric()[0].GetHistogram().GetSampleCount()
+		}
+	}
+	return 0
+}
+
+func TestTxApproveMetrics_observe(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m, err := newTxApproveMetrics(registry)
+	require.NoError(t, err)
+
+	statuses := []sep8Status{
+		sep8StatusRejected,
+		sep8StatusRevised,
+		sep8StatusActionRequired,
+		sep8StatusSuccess,
+		sep8StatusPending,
+	}
+	for i, status := range statuses {
+		m.observe(status, time.Millisecond)
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.decisionsCounter.WithLabelValues(string(status))))
+		assert.Equal(t, uint64(i+1), histogramSampleCount(t, registry))
+	}
+
+	// requests without a decision are only timed
+	m.observe("", time.Millisecond)
+	assert.Equal(t, uint64(len(statuses)+1), histogramSampleCount(t, registry))
+	for _, status := range statuses {
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.decisionsCounter.WithLabelValues(string(status))))
+	}
+
+	// registering twice on the same registry fails
+	_, err = newTxApproveMetrics(registry)
+	require.Error(t, err)
+
+	// a nil receiver is a no-op
+	var nilMetrics *txApproveMetrics
+	nilMetrics.observe(sep8StatusRejected, time.Millisecond)
+}
+
+func TestTxApproveHandler_serveHTTP_metrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m, err := newTxApproveMetrics(registry)
+	require.NoError(t, err)
+
+	handler := txApproveHandler{
+		issuerKP:          keypair.MustRandom(),
+		assetCode:         "FOO",
+		horizonClient:     &horizonclient.MockClient{},
+		networkPassphrase: "Test SDF Network ; September 2015",
+		db:                &sqlx.DB{},
+		kycThreshold:      500,
+		baseURL:           "https://sep8-server.test",
+		metrics:           m,
+	}
+
+	r := httptest.NewRequest("POST", "/tx-approve", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.decisionsCounter.WithLabelValues(string(sep8StatusRejected))))
+	assert.Equal(t, uint64(1), histogramSampleCount(t, registry))
+}
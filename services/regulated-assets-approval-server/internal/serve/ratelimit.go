@@ -0,0 +1,94 @@
+package serve
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// maxTrackedAccounts is the maximum number of accounts whose limiter is kept,
+// above which an arbitrary one is dropped to make room for a new account.
+const maxTrackedAccounts = 10000
+
+type accountLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// accountRateLimiter is a token bucket rate limiter keeping a separate bucket
+// for every account.
+type accountRateLimiter struct {
+	mu          sync.Mutex
+	limit       rate.Limit
+	burst       int
+	maxAccounts int
+	lastSweep   time.Time
+	limiters    map[string]*accountLimiter
+}
+
+// newAccountRateLimiter returns a rate limiter allowing each account up to
+// requestsPerMinute requests per minute.
+func newAccountRateLimiter(requestsPerMinute int) *accountRateLimiter {
+	return &accountRateLimiter{
+		limit:       rate.Every(time.Minute / time.Duration(requestsPerMinute)),
+		burst:       requestsPerMinute,
+		maxAccounts: maxTrackedAccounts,
+		limiters:    map[string]*accountLimiter{},
+	}
+}
+
+// allow reports whether a request of the account can happen at now. If it
+// can't, it also returns how long the account should wait before retrying.
+func (l *accountRateLimiter) allow(account string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// idle accounts are dropped at most once per refill period, so the
+	// limiters aren't scanned on every request
+	if now.Sub(l.lastSweep) >= l.refillPeriod() {
+		l.removeIdle(now)
+		l.lastSweep = now
+	}
+
+	al, ok := l.limiters[account]
+	if !ok {
+		if len(l.limiters) >= l.maxAccounts {
+			l.removeAny()
+		}
+		al = &accountLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.limiters[account] = al
+	}
+	al.lastSeen = now
+
+	reservation := al.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// refillPeriod returns how long it takes for an empty bucket to be refilled.
+func (l *accountRateLimiter) refillPeriod() time.Duration {
+	return time.Duration(float64(l.burst) / float64(l.limit) * float64(time.Second))
+}
+
+// removeIdle drops the limiters of the accounts whose bucket has been refilled
+// since they were last seen, as they behave as new ones.
+func (l *accountRateLimiter) removeIdle(now time.Time) {
+	refill := l.refillPeriod()
+	for account, al := range l.limiters {
+		if now.Sub(al.lastSeen) >= refill {
+			delete(l.limiters, account)
+		}
+	}
+}
+
+// removeAny drops the limiter of an arbitrary account.
+func (l *accountRateLimiter) removeAny() {
+	for account := range l.limiters {
+		delete(l.limiters, account)
+		return
+	}
+}
@@ -0,0 +1,87 @@
+package serve
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountRateLimiter_allow(t *testing.T) {
+	limiter := newAccountRateLimiter(3)
+	now := time.Unix(1600000000, 0)
+
+	// the first requests within the burst are allowed
+	for i := 0; i < 3; i++ {
+		allowed, retryAfter := limiter.allow("account-a", now)
+		require.True(t, allowed)
+		require.Zero(t, retryAfter)
+	}
+
+	// the next one is throttled until a token is refilled
+	allowed, retryAfter := limiter.allow("account-a", now)
+	require.False(t, allowed)
+	require.Equal(t, 20*time.Second, retryAfter)
+
+	// throttled requests don't consume tokens
+	allowed, retryAfter = limiter.allow("account-a", now.Add(10*time.Second))
+	require.False(t, allowed)
+	require.Equal(t, 10*time.Second, retryAfter)
+
+	// other accounts are unaffected
+	allowed, _ = limiter.allow("account-b", now)
+	require.True(t, allowed)
+
+	// requests are allowed again once a token is refilled
+	allowed, _ = limiter.allow("account-a", now.Add(20*time.Second))
+	require.True(t, allowed)
+	allowed, _ = limiter.allow("account-a", now.Add(20*time.Second))
+	require.False(t, allowed)
+}
+
+func TestAccountRateLimiter_removeIdle(t *testing.T) {
+	limiter := newAccountRateLimiter(3)
+	now := time.Unix(1600000000, 0)
+
+	limiter.allow("account-a", now)
+	limiter.allow("account-b", now.Add(30*time.Second))
+	require.Len(t, limiter.limiters, 2)
+
+	// account-a bucket has been refilled, account-b's hasn't yet
+	limiter.removeIdle(now.Add(time.Minute))
+	require.Len(t, limiter.limiters, 1)
+	require.Contains(t, limiter.limiters, "account-b")
+}
+
+func TestAccountRateLimiter_allowRemovesIdlePeriodically(t *testing.T) {
+	limiter := newAccountRateLimiter(3)
+	now := time.Unix(1600000000, 0)
+
+	limiter.allow("account-a", now)
+	limiter.allow("account-b", now.Add(30*time.Second))
+	require.Len(t, limiter.limiters, 2)
+
+	// account-a is idle but the limiters were swept less than a minute ago
+	limiter.allow("account-b", now.Add(59*time.Second))
+	require.Len(t, limiter.limiters, 2)
+
+	limiter.allow("account-b", now.Add(time.Minute))
+	require.Len(t, limiter.limiters, 1)
+	require.Contains(t, limiter.limiters, "account-b")
+}
+
+func TestAccountRateLimiter_maxAccounts(t *testing.T) {
+	limiter := newAccountRateLimiter(3)
+	limiter.maxAccounts = 2
+	now := time.Unix(1600000000, 0)
+
+	limiter.allow("account-a", now)
+	limiter.allow("account-b", now)
+	limiter.allow("account-c", now)
+	require.Len(t, limiter.limiters, 2)
+	require.Contains(t, limiter.limiters, "account-c")
+
+	// known accounts don't cause any other account to be dropped
+	limiter.allow("account-c", now)
+	require.Len(t, limiter.limiters, 2)
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/stellar/go/amount"
 	"github.com/stellar/go/clients/horizonclient"
 	"github.com/stellar/go/keypair"
@@ -21,22 +23,34 @@ import (
 )
 
 type Options struct {
-	AssetCode                         string
-	BaseURL                           string
-	DatabaseURL                       string
-	FriendbotPaymentAmount            int
-	HorizonURL                        string
-	IssuerAccountSecret               string
-	KYCRequiredPaymentAmountThreshold string
-	NetworkPassphrase                 string
-	Port                              int
+	AdminPort                            int
+	AssetCode                            string
+	BaseURL                              string
+	DatabaseURL                          string
+	FriendbotPaymentAmount               int
+	HorizonURL                           string
+	IdempotencyTTL                       int
+	IssuerAccountSecret                  string
+	KYCRequiredPaymentAmountThreshold    string
+	MaxOperations                        int
+	MaxTimeout                           int
+	NetworkPassphrase                    string
+	Port                                 int
+	RateLimit                            int
+	RequireMinTime                       bool
+	ReviewRequiredPaymentAmountThreshold string
 }
 
 func Serve(opts Options) {
+	metricsRegistry := prometheus.NewRegistry()
+	if opts.AdminPort != 0 {
+		go serveAdmin(opts, metricsRegistry)
+	}
+
 	listenAddr := fmt.Sprintf(":%d", opts.Port)
 	serverConfig := supporthttp.Config{
 		ListenAddr:          listenAddr,
-		Handler:             handleHTTP(opts),
+		Handler:             handleHTTP(opts, metricsRegistry),
 		TCPKeepAlive:        time.Minute * 3,
 		ShutdownGracePeriod: time.Second * 50,
 		ReadTimeout:         time.Second * 5,
@@ -53,7 +67,21 @@ func Serve(opts Options) {
 	supporthttp.Run(serverConfig)
 }
 
-func handleHTTP(opts Options) http.Handler {
+func serveAdmin(opts Options, metricsGatherer prometheus.Gatherer) {
+	mux := chi.NewMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metricsGatherer, promhttp.HandlerOpts{}))
+
+	listenAddr := fmt.Sprintf(":%d", opts.AdminPort)
+	supporthttp.Run(supporthttp.Config{
+		ListenAddr: listenAddr,
+		Handler:    mux,
+		OnStarting: func() {
+			log.Infof("Starting admin port server on %s", listenAddr)
+		},
+	})
+}
+
+func handleHTTP(opts Options, metricsRegistry prometheus.Registerer) http.Handler {
 	issuerKP, err := keypair.ParseFull(opts.IssuerAccountSecret)
 	if err != nil {
 		log.Fatal(errors.Wrap(err, "parsing secret"))
@@ -62,6 +90,16 @@ func handleHTTP(opts Options) http.Handler {
 	if err != nil {
 		log.Fatal(errors.Wrapf(err, "%s cannot be parsed as a Stellar amount", opts.KYCRequiredPaymentAmountThreshold))
 	}
+	var parsedReviewRequiredPaymentThreshold int64
+	if opts.ReviewRequiredPaymentAmountThreshold != "" {
+		parsedReviewRequiredPaymentThreshold, err = amount.ParseInt64(opts.ReviewRequiredPaymentAmountThreshold)
+		if err != nil {
+			log.Fatal(errors.Wrapf(err, "%s cannot be parsed as a Stellar amount", opts.ReviewRequiredPaymentAmountThreshold))
+		}
+	}
+	if opts.MaxOperations < minMaxOperations {
+		log.Fatalf("max operations cannot be less than %d", minMaxOperations)
+	}
 	db, err := db.Open(opts.DatabaseURL)
 	if err != nil {
 		log.Fatal(errors.Wrap(err, "error parsing database url"))
@@ -71,6 +109,18 @@ func handleHTTP(opts Options) http.Handler {
 	if err != nil {
 		log.Warn("Error pinging to Database: ", err)
 	}
+	txApproveMetrics, err := newTxApproveMetrics(metricsRegistry)
+	if err != nil {
+		log.Fatal(errors.Wrap(err, "creating tx approve metrics"))
+	}
+	var txApproveRateLimiter *accountRateLimiter
+	if opts.RateLimit > 0 {
+		txApproveRateLimiter = newAccountRateLimiter(opts.RateLimit)
+	}
+	var txApproveResponseCache *txApprovalCache
+	if opts.IdempotencyTTL > 0 {
+		txApproveResponseCache = newTxApprovalCache(time.Duration(opts.IdempotencyTTL) * time.Second)
+	}
 	mux := chi.NewMux()
 
 	mux.Use(middleware.RequestID)
@@ -101,7 +151,15 @@ func handleHTTP(opts Options) http.Handler {
 		networkPassphrase: opts.NetworkPassphrase,
 		db:                db,
 		kycThreshold:      parsedKYCRequiredPaymentThreshold,
+		reviewThreshold:   parsedReviewRequiredPaymentThreshold,
 		baseURL:           opts.BaseURL,
+		maxOperations:     opts.MaxOperations,
+		maxTimeout:        time.Duration(opts.MaxTimeout) * time.Second,
+		requireMinTime:    opts.RequireMinTime,
+		rateLimiter:       txApproveRateLimiter,
+		responseCache:     txApproveResponseCache,
+		authRequiredCache: newAuthRequiredCache(authRequiredCacheTTL),
+		metrics:           txApproveMetrics,
 	}.ServeHTTP)
 	mux.Route("/kyc-status", func(mux chi.Router) {
 		mux.Post("/{callback_id}", kycstatus.PostHandler{
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math"
+	"math/big"
 	"net/http"
-	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -26,9 +28,30 @@ type txApproveHandler struct {
 	networkPassphrase string
 	db                *sqlx.DB
 	kycThreshold      int64
+	reviewThreshold   int64
 	baseURL           string
+	maxOperations     int
+	maxTimeout        time.Duration
+	requireMinTime    bool
+	rateLimiter       *accountRateLimiter
+	responseCache     *txApprovalCache
+	authRequiredCache *authRequiredCache
+	metrics           *txApproveMetrics
 }
 
+// defaultMaxOperations is the maximum number of operations inspected in a
+// transaction when none is configured. It comfortably fits the 5 operations of
+// a compliant transaction.
+const defaultMaxOperations = 10
+
+// minMaxOperations is the lowest maximum number of operations that can be
+// configured, as compliant transactions have up to 5 operations.
+const minMaxOperations = 5
+
+// defaultRevisedTxTimeout is the timeout of the revised transactions when it's
+// not restricted by a lower max timeout.
+const defaultRevisedTxTimeout = 300 * time.Second
+
 type txApproveRequest struct {
 	Tx string `json:"tx" form:"tx"`
 }
@@ -41,6 +64,9 @@ func (h txApproveHandler) validate() error {
 	if h.assetCode == "" {
 		return errors.New("asset code cannot be empty")
 	}
+	if len(h.assetCode) > 12 {
+		return errors.New("asset code cannot be longer than 12 characters")
+	}
 	if h.horizonClient == nil {
 		return errors.New("horizon client cannot be nil")
 	}
@@ -53,9 +79,21 @@ func (h txApproveHandler) validate() error {
 	if h.kycThreshold <= 0 {
 		return errors.New("kyc threshold cannot be less than or equal to zero")
 	}
+	if h.reviewThreshold < 0 {
+		return errors.New("review threshold cannot be less than zero")
+	}
+	if h.reviewThreshold >= h.kycThreshold {
+		return errors.New("review threshold must be less than the kyc threshold")
+	}
 	if h.baseURL == "" {
 		return errors.New("base url cannot be empty")
 	}
+	if h.maxOperations != 0 && h.maxOperations < minMaxOperations {
+		return errors.Errorf("max operations cannot be less than %d", minMaxOperations)
+	}
+	if h.maxTimeout < 0 {
+		return errors.New("max timeout cannot be less than zero")
+	}
 	return nil
 }
 
@@ -76,12 +114,15 @@ func (h txApproveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	start := time.Now()
 	txApproveResp, err := h.txApprove(ctx, in)
 	if err != nil {
+		h.metrics.observe("", time.Since(start))
 		log.Ctx(ctx).Error(errors.Wrap(err, "validating the input transaction for approval"))
 		httperror.InternalServer.Render(w)
 		return
 	}
+	h.metrics.observe(txApproveResp.Status, time.Since(start))
 
 	txApproveResp.Render(w)
 }
@@ -106,6 +147,20 @@ func (h txApproveHandler) validateInput(ctx context.Context, in txApproveRequest
 		return NewRejectedTxApprovalResponse(`Invalid parameter "tx".`), nil
 	}
 
+	// bound the number of operations before inspecting them any further
+	maxOperations := h.maxOperations
+	if maxOperations == 0 {
+		maxOperations = defaultMaxOperations
+	}
+	if len(tx.Operations()) > maxOperations {
+		log.Ctx(ctx).Errorf("transaction has %d operations, exceeding the maximum of %d", len(tx.Operations()), maxOperations)
+		return NewRejectedTxApprovalResponse(fmt.Sprintf("Transactions can't have more than %d operations.", maxOperations)), nil
+	}
+
+	if rejectedResponse := h.validateTimeBounds(ctx, tx.Timebounds(), time.Now()); rejectedResponse != nil {
+		return rejectedResponse, nil
+	}
+
 	if tx.SourceAccount().AccountID == h.issuerKP.Address() {
 		log.Ctx(ctx).Errorf("transaction sourceAccount is the same as the server issuer account %s", h.issuerKP.Address())
 		return NewRejectedTxApprovalResponse("Transaction source account is invalid."), nil
@@ -126,6 +181,28 @@ func (h txApproveHandler) validateInput(ctx context.Context, in txApproveRequest
 	return nil, tx
 }
 
+// validateTimeBounds returns a rejected response if the transaction has already
+// expired, if its max time is further in the future than the configured
+// maxTimeout allows, or if it has no min time while one is required.
+func (h txApproveHandler) validateTimeBounds(ctx context.Context, timeBounds txnbuild.TimeBounds, now time.Time) *txApprovalResponse {
+	if timeBounds.MaxTime != 0 && timeBounds.MaxTime < now.Unix() {
+		log.Ctx(ctx).Errorf("transaction max time %d is in the past", timeBounds.MaxTime)
+		return NewRejectedTxApprovalResponse("Transaction has expired.")
+	}
+
+	if h.maxTimeout > 0 && (timeBounds.MaxTime == 0 || timeBounds.MaxTime > now.Add(h.maxTimeout).Unix()) {
+		log.Ctx(ctx).Errorf("transaction max time %d exceeds the max timeout of %s", timeBounds.MaxTime, h.maxTimeout)
+		return NewRejectedTxApprovalResponse(fmt.Sprintf("Transaction max time can't be more than %d seconds in the future.", int64(h.maxTimeout/time.Second)))
+	}
+
+	if h.requireMinTime && timeBounds.MinTime == 0 {
+		log.Ctx(ctx).Error("transaction is missing a min time")
+		return NewRejectedTxApprovalResponse("Transaction must have a min time.")
+	}
+
+	return nil
+}
+
 // txApprove is called to validate the input transaction.
 func (h txApproveHandler) txApprove(ctx context.Context, in txApproveRequest) (resp *txApprovalResponse, err error) {
 	defer func() {
@@ -141,6 +218,33 @@ func (h txApproveHandler) txApprove(ctx context.Context, in txApproveRequest) (r
 		return rejectedResponse, nil
 	}
 
+	if h.rateLimiter != nil {
+		sourceAccount := tx.SourceAccount().AccountID
+		if allowed, retryAfter := h.rateLimiter.allow(sourceAccount, time.Now()); !allowed {
+			log.Ctx(ctx).Errorf("rate limit exceeded for source account %s", sourceAccount)
+			retryAfterSeconds := int64(math.Ceil(retryAfter.Seconds()))
+			return NewRejectedTxApprovalResponse(fmt.Sprintf("Too many requests for this source account, please retry in %d seconds.", retryAfterSeconds)), nil
+		}
+	}
+
+	// transactions signed by the issuer get the same response when resubmitted,
+	// as the signature was already handed out. The other decisions, including
+	// revisions, may change with the KYC status.
+	if h.responseCache != nil {
+		txHash, hashErr := tx.HashHex(h.networkPassphrase)
+		if hashErr != nil {
+			return nil, errors.Wrap(hashErr, "hashing transaction")
+		}
+		if cachedResp := h.responseCache.get(txHash, time.Now()); cachedResp != nil {
+			return cachedResp, nil
+		}
+		defer func() {
+			if err == nil && resp != nil && resp.Status == sep8StatusSuccess {
+				h.responseCache.add(txHash, resp, time.Now())
+			}
+		}()
+	}
+
 	txSuccessResp, err := h.handleSuccessResponseIfNeeded(ctx, tx)
 	if err != nil {
 		return nil, errors.Wrap(err, "checking if transaction in request was compliant")
@@ -154,23 +258,25 @@ func (h txApproveHandler) txApprove(ctx context.Context, in txApproveRequest) (r
 		return NewRejectedTxApprovalResponse("Please submit a transaction with exactly one operation of type payment."), nil
 	}
 
-	paymentOp, ok := tx.Operations()[0].(*txnbuild.Payment)
-	if !ok {
+	paymentOp := tx.Operations()[0]
+	if !isPaymentOperation(paymentOp) {
 		log.Ctx(ctx).Error("transaction does not contain a payment operation")
 		return NewRejectedTxApprovalResponse("There is one or more unauthorized operations in the provided transaction."), nil
 	}
-	paymentSource := paymentOp.SourceAccount
+	paymentSource := paymentOp.GetSourceAccount()
 	if paymentSource == "" {
 		paymentSource = tx.SourceAccount().AccountID
 	}
 
-	if paymentOp.Destination == h.issuerKP.Address() {
+	if paymentDestination(paymentOp) == h.issuerKP.Address() {
 		return NewRejectedTxApprovalResponse("Can't transfer asset to its issuer."), nil
 	}
 
 	// validate payment asset is the one supported by the issuer
 	issuerAddress := h.issuerKP.Address()
-	if paymentOp.Asset.GetCode() != h.assetCode || paymentOp.Asset.GetIssuer() != issuerAddress {
+	regulatedAsset := txnbuild.CreditAsset{Code: h.assetCode, Issuer: issuerAddress}
+	payment := parseRegulatedPayment(paymentOp, paymentSource, regulatedAsset)
+	if payment == nil {
 		log.Ctx(ctx).Error(`the payment asset is not supported by this issuer`)
 		return NewRejectedTxApprovalResponse("The payment asset is not supported by this issuer."), nil
 	}
@@ -186,7 +292,7 @@ func (h txApproveHandler) txApprove(ctx context.Context, in txApproveRequest) (r
 		return NewRejectedTxApprovalResponse("Invalid transaction sequence number."), nil
 	}
 
-	actionRequiredResponse, err := h.handleActionRequiredResponseIfNeeded(ctx, paymentSource, paymentOp)
+	actionRequiredResponse, err := h.handleActionRequiredResponseIfNeeded(ctx, paymentSource, payment.amount)
 	if err != nil {
 		return nil, errors.Wrap(err, "handling KYC required payment")
 	}
@@ -194,40 +300,46 @@ func (h txApproveHandler) txApprove(ctx context.Context, in txApproveRequest) (r
 		return actionRequiredResponse, nil
 	}
 
+	// assets that don't require authorization only need the KYC checks above,
+	// so the original transaction can be signed without being revised.
+	authRequired, err := h.isAuthorizationRequired()
+	if err != nil {
+		return nil, errors.Wrap(err, "checking if the asset requires authorization")
+	}
+	if !authRequired {
+		// the original transaction is signed as is, so it must expire as soon
+		// as a revised one would.
+		timeout := h.revisedTxTimeout()
+		maxTime := tx.Timebounds().MaxTime
+		if maxTime == 0 || maxTime > time.Now().Unix()+timeout {
+			log.Ctx(ctx).Errorf("transaction max time %d exceeds the signing timeout of %ds", maxTime, timeout)
+			return NewRejectedTxApprovalResponse(fmt.Sprintf("Transaction max time can't be more than %d seconds in the future.", timeout)), nil
+		}
+
+		tx, err = tx.Sign(h.networkPassphrase, h.issuerKP)
+		if err != nil {
+			return nil, errors.Wrap(err, "signing transaction")
+		}
+		txe, err := tx.Base64()
+		if err != nil {
+			return nil, errors.Wrap(err, "encoding transaction")
+		}
+
+		return NewSuccessTxApprovalResponse(txe, "Transaction is compliant and signed by the issuer."), nil
+	}
+
 	// build the transaction
-	revisedOperations := []txnbuild.Operation{
-		&txnbuild.AllowTrust{
-			Trustor:       paymentSource,
-			Type:          paymentOp.Asset,
-			Authorize:     true,
-			SourceAccount: issuerAddress,
-		},
-		&txnbuild.AllowTrust{
-			Trustor:       paymentOp.Destination,
-			Type:          paymentOp.Asset,
-			Authorize:     true,
-			SourceAccount: issuerAddress,
-		},
-		paymentOp,
-		&txnbuild.AllowTrust{
-			Trustor:       paymentOp.Destination,
-			Type:          paymentOp.Asset,
-			Authorize:     false,
-			SourceAccount: issuerAddress,
-		},
-		&txnbuild.AllowTrust{
-			Trustor:       paymentSource,
-			Type:          paymentOp.Asset,
-			Authorize:     false,
-			SourceAccount: issuerAddress,
-		},
+	revisedOperations := authorizationSandwich(paymentOp, payment.trustors, regulatedAsset)
+	revisedTimeBounds := txnbuild.NewTimeout(h.revisedTxTimeout())
+	if h.requireMinTime {
+		revisedTimeBounds.MinTime = time.Now().Unix()
 	}
 	revisedTx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
 		SourceAccount:        &acc,
 		IncrementSequenceNum: true,
 		Operations:           revisedOperations,
 		BaseFee:              300,
-		Preconditions:        txnbuild.Preconditions{TimeBounds: txnbuild.NewTimeout(300)},
+		Preconditions:        txnbuild.Preconditions{TimeBounds: revisedTimeBounds},
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "building transaction")
@@ -245,14 +357,61 @@ func (h txApproveHandler) txApprove(ctx context.Context, in txApproveRequest) (r
 	return NewRevisedTxApprovalResponse(txe), nil
 }
 
+// revisedTxTimeout returns the timeout in seconds of the revised transactions,
+// which can't exceed the max timeout or they would be rejected when submitted
+// back to the server.
+func (h txApproveHandler) revisedTxTimeout() int64 {
+	timeout := defaultRevisedTxTimeout
+	if h.maxTimeout > 0 && h.maxTimeout < timeout {
+		timeout = h.maxTimeout
+	}
+	return int64(timeout / time.Second)
+}
+
+// isAuthorizationRequired checks the flags of the regulated asset in Horizon
+// to decide if payments need to be wrapped by the AllowTrust operations. If the
+// asset is not known by Horizon yet, authorization is assumed to be required.
+// The result is kept in the handler cache, if any.
+func (h txApproveHandler) isAuthorizationRequired() (bool, error) {
+	if h.authRequiredCache != nil {
+		if authRequired, ok := h.authRequiredCache.get(time.Now()); ok {
+			return authRequired, nil
+		}
+	}
+
+	assets, err := h.horizonClient.Assets(horizonclient.AssetRequest{
+		ForAssetCode:   h.assetCode,
+		ForAssetIssuer: h.issuerKP.Address(),
+		Limit:          1,
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "getting detail for asset %s:%s", h.assetCode, h.issuerKP.Address())
+	}
+	authRequired := true
+	if len(assets.Embedded.Records) > 0 {
+		authRequired = assets.Embedded.Records[0].Flags.AuthRequired
+	}
+
+	if h.authRequiredCache != nil {
+		h.authRequiredCache.set(authRequired, time.Now())
+	}
+	return authRequired, nil
+}
+
 // handleActionRequiredResponseIfNeeded validates and returns an action_required
-// response if the payment requires KYC.
-func (h txApproveHandler) handleActionRequiredResponseIfNeeded(ctx context.Context, stellarAddress string, paymentOp *txnbuild.Payment) (*txApprovalResponse, error) {
-	paymentAmount, err := amount.ParseInt64(paymentOp.Amount)
+// response if the payment requires KYC. Payments above the review threshold, if
+// one is configured, but not above the KYC threshold only require additional
+// information to be submitted.
+func (h txApproveHandler) handleActionRequiredResponseIfNeeded(ctx context.Context, stellarAddress string, amountStr string) (*txApprovalResponse, error) {
+	paymentAmount, err := amount.ParseInt64(amountStr)
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing payment amount from string to Int64")
 	}
-	if paymentAmount <= h.kycThreshold {
+	threshold := h.kycThreshold
+	if h.reviewThreshold > 0 {
+		threshold = h.reviewThreshold
+	}
+	if paymentAmount <= threshold {
 		return nil, nil
 	}
 
@@ -264,17 +423,17 @@ func (h txApproveHandler) handleActionRequiredResponseIfNeeded(ctx context.Conte
 			ON CONFLICT(stellar_address) DO NOTHING
 			RETURNING *
 		)
-		SELECT callback_id, approved_at, rejected_at, pending_at FROM new_row
+		SELECT callback_id, kyc_submitted_at, approved_at, rejected_at, pending_at FROM new_row
 		UNION
-		SELECT callback_id, approved_at, rejected_at, pending_at
+		SELECT callback_id, kyc_submitted_at, approved_at, rejected_at, pending_at
 		FROM accounts_kyc_status
 		WHERE stellar_address = $1
 	`
 	var (
-		callbackID                        string
-		approvedAt, rejectedAt, pendingAt sql.NullTime
+		callbackID                                     string
+		submittedAt, approvedAt, rejectedAt, pendingAt sql.NullTime
 	)
-	err = h.db.QueryRowContext(ctx, q, stellarAddress, intendedCallbackID).Scan(&callbackID, &approvedAt, &rejectedAt, &pendingAt)
+	err = h.db.QueryRowContext(ctx, q, stellarAddress, intendedCallbackID).Scan(&callbackID, &submittedAt, &approvedAt, &rejectedAt, &pendingAt)
 	if err != nil {
 		return nil, errors.Wrap(err, "inserting new row into accounts_kyc_status table")
 	}
@@ -283,15 +442,26 @@ func (h txApproveHandler) handleActionRequiredResponseIfNeeded(ctx context.Conte
 		return nil, nil
 	}
 
-	kycThreshold, err := convertAmountToReadableString(h.kycThreshold)
-	if err != nil {
-		return nil, errors.Wrap(err, "converting kycThreshold to human readable string")
-	}
+	readableThreshold := convertAmountToReadableString(threshold)
 
 	if rejectedAt.Valid {
-		return NewRejectedTxApprovalResponse(fmt.Sprintf("Your KYC was rejected and you're not authorized for operations above %s %s.", kycThreshold, h.assetCode)), nil
+		return NewRejectedTxApprovalResponse(fmt.Sprintf("Your KYC was rejected and you're not authorized for operations above %s %s.", readableThreshold, h.assetCode)), nil
+	}
+
+	if paymentAmount <= h.kycThreshold {
+		if submittedAt.Valid {
+			return nil, nil
+		}
+
+		return NewActionRequiredTxApprovalResponse(
+			fmt.Sprintf(`Payments exceeding %s %s require additional information. Please provide your full name.`, readableThreshold, h.assetCode),
+			fmt.Sprintf("%s/kyc-status/%s", h.baseURL, callbackID),
+			[]string{"full_name"},
+		), nil
 	}
 
+	kycThreshold := convertAmountToReadableString(h.kycThreshold)
+
 	if pendingAt.Valid {
 		return NewPendingTxApprovalResponse(fmt.Sprintf("Your account could not be verified as approved nor rejected and was marked as pending. You will need staff authorization for operations above %s %s.", kycThreshold, h.assetCode)), nil
 	}
@@ -306,7 +476,7 @@ func (h txApproveHandler) handleActionRequiredResponseIfNeeded(ctx context.Conte
 // handleSuccessResponseIfNeeded inspects the incoming transaction and returns a
 // "success" response if it's already compliant with the SEP-8 authorization spec.
 func (h txApproveHandler) handleSuccessResponseIfNeeded(ctx context.Context, tx *txnbuild.Transaction) (*txApprovalResponse, error) {
-	if len(tx.Operations()) != 5 {
+	if len(tx.Operations()) != 3 && len(tx.Operations()) != 5 {
 		return nil, nil
 	}
 
@@ -315,7 +485,7 @@ func (h txApproveHandler) handleSuccessResponseIfNeeded(ctx context.Context, tx
 		return rejectedResp, nil
 	}
 
-	if paymentOp.Destination == h.issuerKP.Address() {
+	if paymentDestination(paymentOp) == h.issuerKP.Address() {
 		return NewRejectedTxApprovalResponse("Can't transfer asset to its issuer."), nil
 	}
 
@@ -329,7 +499,11 @@ func (h txApproveHandler) handleSuccessResponseIfNeeded(ctx context.Context, tx
 		return NewRejectedTxApprovalResponse("Invalid transaction sequence number."), nil
 	}
 
-	kycRequiredResponse, err := h.handleActionRequiredResponseIfNeeded(ctx, paymentSource, paymentOp)
+	payment := parseRegulatedPayment(paymentOp, paymentSource, txnbuild.CreditAsset{Code: h.assetCode, Issuer: h.issuerKP.Address()})
+	if payment == nil {
+		return NewRejectedTxApprovalResponse("The payment asset is not supported by this issuer."), nil
+	}
+	kycRequiredResponse, err := h.handleActionRequiredResponseIfNeeded(ctx, paymentSource, payment.amount)
 	if err != nil {
 		return nil, errors.Wrap(err, "handling KYC required payment")
 	}
@@ -351,81 +525,181 @@ func (h txApproveHandler) handleSuccessResponseIfNeeded(ctx context.Context, tx
 }
 
 // validateTransactionOperationsForSuccess checks if the incoming transaction
-// operations are compliant with the anchor's SEP-8 policy.
-func validateTransactionOperationsForSuccess(ctx context.Context, tx *txnbuild.Transaction, issuerAddress string) (resp *txApprovalResponse, paymentOp *txnbuild.Payment, paymentSource string) {
-	if len(tx.Operations()) != 5 {
+// operations are compliant with the anchor's SEP-8 policy, i.e. a payment
+// operation wrapped by the AllowTrust operations authorizing and deauthorizing
+// the accounts transferring the regulated asset.
+func validateTransactionOperationsForSuccess(ctx context.Context, tx *txnbuild.Transaction, issuerAddress string) (resp *txApprovalResponse, paymentOp txnbuild.Operation, paymentSource string) {
+	operations := tx.Operations()
+	if len(operations) != 3 && len(operations) != 5 {
 		return NewRejectedTxApprovalResponse("Unsupported number of operations."), nil, ""
 	}
 
 	// extract the payment operation and payment source account.
-	paymentOp, ok := tx.Operations()[2].(*txnbuild.Payment)
-	if !ok {
-		log.Ctx(ctx).Error(`third operation is not of type payment`)
+	paymentOp = operations[len(operations)/2]
+	if !isPaymentOperation(paymentOp) {
+		log.Ctx(ctx).Error(`middle operation is not of type payment`)
 		return NewRejectedTxApprovalResponse("There are one or more unexpected operations in the provided transaction."), nil, ""
 	}
-	paymentSource = paymentOp.SourceAccount
+	paymentSource = paymentOp.GetSourceAccount()
 	if paymentSource == "" {
 		paymentSource = tx.SourceAccount().AccountID
 	}
 
-	assetCode := paymentOp.Asset.GetCode()
+	allowTrust, ok := operations[0].(*txnbuild.AllowTrust)
+	if !ok || allowTrust.Type == nil {
+		return NewRejectedTxApprovalResponse("There are one or more unexpected operations in the provided transaction."), nil, ""
+	}
+	asset := txnbuild.CreditAsset{Code: allowTrust.Type.GetCode(), Issuer: issuerAddress}
 
-	operationsValid := func() bool {
-		op0, ok := tx.Operations()[0].(*txnbuild.AllowTrust)
-		if !ok ||
-			op0.Trustor != paymentSource ||
-			op0.Type.GetCode() != assetCode ||
-			!op0.Authorize ||
-			op0.SourceAccount != issuerAddress {
-			return false
+	payment := parseRegulatedPayment(paymentOp, paymentSource, asset)
+	if payment == nil || !operationsMatch(operations, authorizationSandwich(paymentOp, payment.trustors, asset)) {
+		return NewRejectedTxApprovalResponse("There are one or more unexpected operations in the provided transaction."), nil, ""
+	}
+
+	return nil, paymentOp, paymentSource
+}
+
+// operationsMatch returns true if the AllowTrust operations in got authorize
+// the same trustors as the ones in want, and the other operations are the same.
+func operationsMatch(got, want []txnbuild.Operation) bool {
+	if len(got) != len(want) {
+		return false
+	}
+
+	for i := range want {
+		wantAllowTrust, ok := want[i].(*txnbuild.AllowTrust)
+		if !ok {
+			if got[i] != want[i] {
+				return false
+			}
+			continue
 		}
 
-		op1, ok := tx.Operations()[1].(*txnbuild.AllowTrust)
+		gotAllowTrust, ok := got[i].(*txnbuild.AllowTrust)
 		if !ok ||
-			op1.Trustor != paymentOp.Destination ||
-			op1.Type.GetCode() != assetCode ||
-			!op1.Authorize ||
-			op1.SourceAccount != issuerAddress {
+			gotAllowTrust.Trustor != wantAllowTrust.Trustor ||
+			gotAllowTrust.Type == nil ||
+			gotAllowTrust.Type.GetCode() != wantAllowTrust.Type.GetCode() ||
+			gotAllowTrust.Authorize != wantAllowTrust.Authorize ||
+			gotAllowTrust.SourceAccount != wantAllowTrust.SourceAccount {
 			return false
 		}
+	}
 
-		op2, ok := tx.Operations()[2].(*txnbuild.Payment)
-		if !ok || op2 != paymentOp {
-			return false
+	return true
+}
+
+// regulatedPayment describes how a payment operation transfers the regulated
+// asset.
+type regulatedPayment struct {
+	// amount is the maximum amount of the regulated asset transferred.
+	amount string
+	// trustors are the accounts whose trustlines to the regulated asset are
+	// used by the payment, and thus need to be authorized for it to succeed.
+	trustors []string
+}
+
+// isPaymentOperation returns true if op is one of the payment operations
+// supported by this server.
+func isPaymentOperation(op txnbuild.Operation) bool {
+	switch op.(type) {
+	case *txnbuild.Payment, *txnbuild.PathPaymentStrictSend, *txnbuild.PathPaymentStrictReceive:
+		return true
+	default:
+		return false
+	}
+}
+
+// paymentDestination returns the destination account of a payment operation.
+func paymentDestination(op txnbuild.Operation) string {
+	switch op := op.(type) {
+	case *txnbuild.Payment:
+		return op.Destination
+	case *txnbuild.PathPaymentStrictSend:
+		return op.Destination
+	case *txnbuild.PathPaymentStrictReceive:
+		return op.Destination
+	default:
+		return ""
+	}
+}
+
+// parseRegulatedPayment returns how the payment operation op, sent by source,
+// transfers the regulated asset. It returns nil if op doesn't transfer the
+// regulated asset, or transfers an amount of it that can't be bounded, like
+// the amount received through a path_payment_strict_send.
+func parseRegulatedPayment(op txnbuild.Operation, source string, asset txnbuild.CreditAsset) *regulatedPayment {
+	isRegulated := func(a txnbuild.Asset) bool {
+		return a != nil && !a.IsNative() && a.GetCode() == asset.Code && a.GetIssuer() == asset.Issuer
+	}
+
+	switch op := op.(type) {
+	case *txnbuild.Payment:
+		if !isRegulated(op.Asset) {
+			return nil
 		}
+		return &regulatedPayment{amount: op.Amount, trustors: []string{source, op.Destination}}
 
-		op3, ok := tx.Operations()[3].(*txnbuild.AllowTrust)
-		if !ok ||
-			op3.Trustor != paymentOp.Destination ||
-			op3.Type.GetCode() != assetCode ||
-			op3.Authorize ||
-			op3.SourceAccount != issuerAddress {
-			return false
+	case *txnbuild.PathPaymentStrictSend:
+		if !isRegulated(op.SendAsset) {
+			return nil
+		}
+		payment := &regulatedPayment{amount: op.SendAmount, trustors: []string{source}}
+		if isRegulated(op.DestAsset) {
+			payment.trustors = append(payment.trustors, op.Destination)
 		}
+		return payment
 
-		op4, ok := tx.Operations()[4].(*txnbuild.AllowTrust)
-		if !ok ||
-			op4.Trustor != paymentSource ||
-			op4.Type.GetCode() != assetCode ||
-			op4.Authorize ||
-			op4.SourceAccount != issuerAddress {
-			return false
+	case *txnbuild.PathPaymentStrictReceive:
+		payment := &regulatedPayment{}
+		if isRegulated(op.SendAsset) {
+			payment.amount = op.SendMax
+			payment.trustors = append(payment.trustors, source)
+		}
+		if isRegulated(op.DestAsset) {
+			if payment.amount == "" {
+				payment.amount = op.DestAmount
+			}
+			payment.trustors = append(payment.trustors, op.Destination)
+		}
+		if len(payment.trustors) == 0 {
+			return nil
 		}
+		return payment
 
-		return true
-	}()
-	if !operationsValid {
-		return NewRejectedTxApprovalResponse("There are one or more unexpected operations in the provided transaction."), nil, ""
+	default:
+		return nil
 	}
-
-	return nil, paymentOp, paymentSource
 }
 
-func convertAmountToReadableString(threshold int64) (string, error) {
-	amountStr := amount.StringFromInt64(threshold)
-	amountFloat, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil {
-		return "", errors.Wrap(err, "converting threshold amount from string to float")
+// authorizationSandwich wraps the payment operation with AllowTrust operations
+// authorizing the trustors to transact the regulated asset right before it, and
+// deauthorizing them right after it.
+func authorizationSandwich(paymentOp txnbuild.Operation, trustors []string, asset txnbuild.CreditAsset) []txnbuild.Operation {
+	operations := make([]txnbuild.Operation, 0, 2*len(trustors)+1)
+	for _, trustor := range trustors {
+		operations = append(operations, &txnbuild.AllowTrust{
+			Trustor:       trustor,
+			Type:          asset,
+			Authorize:     true,
+			SourceAccount: asset.Issuer,
+		})
+	}
+	operations = append(operations, paymentOp)
+	for i := len(trustors) - 1; i >= 0; i-- {
+		operations = append(operations, &txnbuild.AllowTrust{
+			Trustor:       trustors[i],
+			Type:          asset,
+			Authorize:     false,
+			SourceAccount: asset.Issuer,
+		})
 	}
-	return fmt.Sprintf("%.2f", amountFloat), nil
+	return operations
+}
+
+// convertAmountToReadableString converts an amount in stroops to a string
+// with two decimals, e.g. 5000000000 to "500.00". The amount is rounded
+// without going through a float so half cents are always rounded up.
+func convertAmountToReadableString(threshold int64) string {
+	return big.NewRat(threshold, amount.One).FloatString(2)
 }
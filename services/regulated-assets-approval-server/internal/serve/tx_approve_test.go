@@ -2,8 +2,10 @@ package serve
 
 import (
 	"context"
+	"math"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stellar/go/amount"
 	"github.com/stellar/go/clients/horizonclient"
@@ -30,6 +32,14 @@ func TestTxApproveHandlerValidate(t *testing.T) {
 	err = h.validate()
 	require.EqualError(t, err, "asset code cannot be empty")
 
+	// asset code too long.
+	h = txApproveHandler{
+		issuerKP:  issuerAccKeyPair,
+		assetCode: "FOOBARFOOBARF",
+	}
+	err = h.validate()
+	require.EqualError(t, err, "asset code cannot be longer than 12 characters")
+
 	// No Horizon client.
 	h = txApproveHandler{
 		issuerKP:  issuerAccKeyPair,
@@ -85,6 +95,32 @@ func TestTxApproveHandlerValidate(t *testing.T) {
 	err = h.validate()
 	require.EqualError(t, err, "kyc threshold cannot be less than or equal to zero")
 
+	// Negative reviewThreshold.
+	h = txApproveHandler{
+		issuerKP:          issuerAccKeyPair,
+		assetCode:         "FOOBAR",
+		horizonClient:     &horizonMock,
+		networkPassphrase: network.TestNetworkPassphrase,
+		db:                conn,
+		kycThreshold:      1,
+		reviewThreshold:   -1,
+	}
+	err = h.validate()
+	require.EqualError(t, err, "review threshold cannot be less than zero")
+
+	// reviewThreshold not lower than kycThreshold.
+	h = txApproveHandler{
+		issuerKP:          issuerAccKeyPair,
+		assetCode:         "FOOBAR",
+		horizonClient:     &horizonMock,
+		networkPassphrase: network.TestNetworkPassphrase,
+		db:                conn,
+		kycThreshold:      1,
+		reviewThreshold:   1,
+	}
+	err = h.validate()
+	require.EqualError(t, err, "review threshold must be less than the kyc threshold")
+
 	// no baseURL.
 	h = txApproveHandler{
 		issuerKP:          issuerAccKeyPair,
@@ -97,6 +133,39 @@ func TestTxApproveHandlerValidate(t *testing.T) {
 	err = h.validate()
 	require.EqualError(t, err, "base url cannot be empty")
 
+	// Negative maxOperations.
+	h = txApproveHandler{
+		issuerKP:          issuerAccKeyPair,
+		assetCode:         "FOOBAR",
+		horizonClient:     &horizonMock,
+		networkPassphrase: network.TestNetworkPassphrase,
+		db:                conn,
+		kycThreshold:      1,
+		baseURL:           "https://example.com",
+		maxOperations:     -1,
+	}
+	err = h.validate()
+	require.EqualError(t, err, "max operations cannot be less than 5")
+
+	// maxOperations too low for compliant transactions.
+	h.maxOperations = 4
+	err = h.validate()
+	require.EqualError(t, err, "max operations cannot be less than 5")
+
+	// Negative maxTimeout.
+	h = txApproveHandler{
+		issuerKP:          issuerAccKeyPair,
+		assetCode:         "FOOBAR",
+		horizonClient:     &horizonMock,
+		networkPassphrase: network.TestNetworkPassphrase,
+		db:                conn,
+		kycThreshold:      1,
+		baseURL:           "https://example.com",
+		maxTimeout:        -time.Second,
+	}
+	err = h.validate()
+	require.EqualError(t, err, "max timeout cannot be less than zero")
+
 	// Success.
 	h = txApproveHandler{
 		issuerKP:          issuerAccKeyPair,
@@ -190,6 +259,72 @@ func TestTxApproveHandler_validateInput(t *testing.T) {
 	require.Equal(t, NewRejectedTxApprovalResponse("There are one or more unauthorized operations in the provided transaction."), txApprovalResp)
 	require.Nil(t, gotTx)
 
+	// rejects if the transaction has more operations than the default maximum
+	bumpSequenceOps := make([]txnbuild.Operation, 11)
+	for i := range bumpSequenceOps {
+		bumpSequenceOps[i] = &txnbuild.BumpSequence{}
+	}
+	tx, err = txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &horizon.Account{
+			AccountID: clientKP.Address(),
+			Sequence:  1,
+		},
+		IncrementSequenceNum: true,
+		Preconditions:        txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+		BaseFee:              300,
+		Operations:           bumpSequenceOps,
+	})
+	require.NoError(t, err)
+	txe, err = tx.Base64()
+	require.NoError(t, err)
+
+	in.Tx = txe
+	txApprovalResp, gotTx = h.validateInput(ctx, in)
+	require.Equal(t, NewRejectedTxApprovalResponse("Transactions can't have more than 10 operations."), txApprovalResp)
+	require.Nil(t, gotTx)
+
+	// rejects if the transaction has more operations than the configured maximum
+	tx, err = txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &horizon.Account{
+			AccountID: clientKP.Address(),
+			Sequence:  1,
+		},
+		IncrementSequenceNum: true,
+		Preconditions:        txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+		BaseFee:              300,
+		Operations:           bumpSequenceOps[:2],
+	})
+	require.NoError(t, err)
+	txe, err = tx.Base64()
+	require.NoError(t, err)
+
+	in.Tx = txe
+	h.maxOperations = 1
+	txApprovalResp, gotTx = h.validateInput(ctx, in)
+	require.Equal(t, NewRejectedTxApprovalResponse("Transactions can't have more than 1 operations."), txApprovalResp)
+	require.Nil(t, gotTx)
+	h.maxOperations = 0
+
+	// rejects if the transaction has expired
+	tx, err = txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &horizon.Account{
+			AccountID: clientKP.Address(),
+			Sequence:  1,
+		},
+		IncrementSequenceNum: true,
+		Preconditions:        txnbuild.Preconditions{TimeBounds: txnbuild.NewTimebounds(0, time.Now().Add(-time.Minute).Unix())},
+		BaseFee:              300,
+		Operations:           bumpSequenceOps[:1],
+	})
+	require.NoError(t, err)
+	txe, err = tx.Base64()
+	require.NoError(t, err)
+
+	in.Tx = txe
+	txApprovalResp, gotTx = h.validateInput(ctx, in)
+	require.Equal(t, NewRejectedTxApprovalResponse("Transaction has expired."), txApprovalResp)
+	require.Nil(t, gotTx)
+
 	// validation success
 	tx, err = txnbuild.NewTransaction(txnbuild.TransactionParams{
 		SourceAccount: &horizon.Account{
@@ -217,6 +352,35 @@ func TestTxApproveHandler_validateInput(t *testing.T) {
 	require.Equal(t, gotTx, tx)
 }
 
+func TestTxApproveHandler_validateTimeBounds(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1600000000, 0)
+	h := txApproveHandler{}
+
+	// infinite timeouts are accepted when no max timeout is configured
+	require.Nil(t, h.validateTimeBounds(ctx, txnbuild.NewInfiniteTimeout(), now))
+
+	// rejects expired transactions
+	txApprovalResp := h.validateTimeBounds(ctx, txnbuild.NewTimebounds(0, now.Unix()-1), now)
+	require.Equal(t, NewRejectedTxApprovalResponse("Transaction has expired."), txApprovalResp)
+
+	// rejects max times further in the future than the max timeout
+	h.maxTimeout = 5 * time.Minute
+	txApprovalResp = h.validateTimeBounds(ctx, txnbuild.NewTimebounds(0, now.Add(time.Hour).Unix()), now)
+	require.Equal(t, NewRejectedTxApprovalResponse("Transaction max time can't be more than 300 seconds in the future."), txApprovalResp)
+	txApprovalResp = h.validateTimeBounds(ctx, txnbuild.NewInfiniteTimeout(), now)
+	require.Equal(t, NewRejectedTxApprovalResponse("Transaction max time can't be more than 300 seconds in the future."), txApprovalResp)
+
+	// accepts max times within the max timeout
+	require.Nil(t, h.validateTimeBounds(ctx, txnbuild.NewTimebounds(0, now.Add(5*time.Minute).Unix()), now))
+
+	// rejects missing min times when required
+	h.requireMinTime = true
+	txApprovalResp = h.validateTimeBounds(ctx, txnbuild.NewTimebounds(0, now.Add(time.Minute).Unix()), now)
+	require.Equal(t, NewRejectedTxApprovalResponse("Transaction must have a min time."), txApprovalResp)
+	require.Nil(t, h.validateTimeBounds(ctx, txnbuild.NewTimebounds(now.Unix(), now.Add(time.Minute).Unix()), now))
+}
+
 func TestTxApproveHandler_handleActionRequiredResponseIfNeeded(t *testing.T) {
 	ctx := context.Background()
 	db := dbtest.Open(t)
@@ -238,7 +402,7 @@ func TestTxApproveHandler_handleActionRequiredResponseIfNeeded(t *testing.T) {
 	paymentOp := &txnbuild.Payment{
 		Amount: amount.StringFromInt64(kycThreshold),
 	}
-	txApprovalResp, err := h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), paymentOp)
+	txApprovalResp, err := h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), paymentOp.Amount)
 	require.NoError(t, err)
 	require.Nil(t, txApprovalResp)
 
@@ -246,7 +410,7 @@ func TestTxApproveHandler_handleActionRequiredResponseIfNeeded(t *testing.T) {
 	paymentOp = &txnbuild.Payment{
 		Amount: amount.StringFromInt64(kycThreshold + 1),
 	}
-	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), paymentOp)
+	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), paymentOp.Amount)
 	require.NoError(t, err)
 
 	var callbackID string
@@ -275,7 +439,7 @@ func TestTxApproveHandler_handleActionRequiredResponseIfNeeded(t *testing.T) {
 	`
 	_, err = conn.ExecContext(ctx, q, clientKP.Address())
 	require.NoError(t, err)
-	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), paymentOp)
+	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), paymentOp.Amount)
 	require.NoError(t, err)
 	require.Nil(t, txApprovalResp)
 
@@ -290,7 +454,7 @@ func TestTxApproveHandler_handleActionRequiredResponseIfNeeded(t *testing.T) {
 	`
 	_, err = conn.ExecContext(ctx, q, clientKP.Address())
 	require.NoError(t, err)
-	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), paymentOp)
+	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), paymentOp.Amount)
 	require.NoError(t, err)
 	require.Equal(t, NewRejectedTxApprovalResponse("Your KYC was rejected and you're not authorized for operations above 500.00 FOO."), txApprovalResp)
 
@@ -305,11 +469,98 @@ func TestTxApproveHandler_handleActionRequiredResponseIfNeeded(t *testing.T) {
 	`
 	_, err = conn.ExecContext(ctx, q, clientKP.Address())
 	require.NoError(t, err)
-	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), paymentOp)
+	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), paymentOp.Amount)
 	require.NoError(t, err)
 	require.Equal(t, NewPendingTxApprovalResponse("Your account could not be verified as approved nor rejected and was marked as pending. You will need staff authorization for operations above 500.00 FOO."), txApprovalResp)
 }
 
+func TestTxApproveHandler_handleActionRequiredResponseIfNeeded_reviewThreshold(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	defer db.Close()
+	conn := db.Open()
+	defer conn.Close()
+
+	kycThreshold, err := amount.ParseInt64("500")
+	require.NoError(t, err)
+	reviewThreshold, err := amount.ParseInt64("100")
+	require.NoError(t, err)
+	h := txApproveHandler{
+		assetCode:       "FOO",
+		baseURL:         "https://example.com",
+		kycThreshold:    kycThreshold,
+		reviewThreshold: reviewThreshold,
+		db:              conn,
+	}
+
+	// payments up to the review threshold won't trigger "action_required"
+	clientKP := keypair.MustRandom()
+	txApprovalResp, err := h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), amount.StringFromInt64(reviewThreshold))
+	require.NoError(t, err)
+	require.Nil(t, txApprovalResp)
+
+	// payments between the two thresholds will trigger "action_required" asking for additional information
+	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), amount.StringFromInt64(reviewThreshold+1))
+	require.NoError(t, err)
+
+	var callbackID string
+	q := `SELECT callback_id FROM accounts_kyc_status WHERE stellar_address = $1`
+	err = conn.QueryRowContext(ctx, q, clientKP.Address()).Scan(&callbackID)
+	require.NoError(t, err)
+
+	wantResp := &txApprovalResponse{
+		Status:       sep8StatusActionRequired,
+		Message:      "Payments exceeding 100.00 FOO require additional information. Please provide your full name.",
+		ActionMethod: "POST",
+		StatusCode:   http.StatusOK,
+		ActionURL:    "https://example.com/kyc-status/" + callbackID,
+		ActionFields: []string{"full_name"},
+	}
+	require.Equal(t, wantResp, txApprovalResp)
+
+	// payments above both thresholds will trigger "action_required" asking for KYC
+	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), amount.StringFromInt64(kycThreshold+1))
+	require.NoError(t, err)
+	wantResp = &txApprovalResponse{
+		Status:       sep8StatusActionRequired,
+		Message:      "Payments exceeding 500.00 FOO require KYC approval. Please provide an email address.",
+		ActionMethod: "POST",
+		StatusCode:   http.StatusOK,
+		ActionURL:    "https://example.com/kyc-status/" + callbackID,
+		ActionFields: []string{"email_address"},
+	}
+	require.Equal(t, wantResp, txApprovalResp)
+
+	// once the additional information was submitted, payments between the two thresholds won't trigger "action_required"
+	q = `
+		UPDATE accounts_kyc_status
+		SET kyc_submitted_at = NOW()
+		WHERE stellar_address = $1
+	`
+	_, err = conn.ExecContext(ctx, q, clientKP.Address())
+	require.NoError(t, err)
+	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), amount.StringFromInt64(reviewThreshold+1))
+	require.NoError(t, err)
+	require.Nil(t, txApprovalResp)
+
+	// but payments above both thresholds still require KYC approval
+	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), amount.StringFromInt64(kycThreshold+1))
+	require.NoError(t, err)
+	require.Equal(t, wantResp, txApprovalResp)
+
+	// if KYC was previously rejected, payments between the two thresholds will return a "rejected" response
+	q = `
+		UPDATE accounts_kyc_status
+		SET rejected_at = NOW()
+		WHERE stellar_address = $1
+	`
+	_, err = conn.ExecContext(ctx, q, clientKP.Address())
+	require.NoError(t, err)
+	txApprovalResp, err = h.handleActionRequiredResponseIfNeeded(ctx, clientKP.Address(), amount.StringFromInt64(reviewThreshold+1))
+	require.NoError(t, err)
+	require.Equal(t, NewRejectedTxApprovalResponse("Your KYC was rejected and you're not authorized for operations above 100.00 FOO."), txApprovalResp)
+}
+
 func TestTxApproveHandler_txApprove_rejected(t *testing.T) {
 	ctx := context.Background()
 	db := dbtest.Open(t)
@@ -486,26 +737,676 @@ func TestTxApproveHandler_txApprove_rejected(t *testing.T) {
 		},
 	)
 	require.NoError(t, err)
-	txe, err = tx.Base64()
+	txe, err = tx.Base64()
+	require.NoError(t, err)
+
+	txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: txe})
+	require.NoError(t, err)
+	assert.Equal(t, NewRejectedTxApprovalResponse("Invalid transaction sequence number."), txApprovalResp)
+}
+
+func TestTxApproveHandler_txApprove_success(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	defer db.Close()
+	conn := db.Open()
+	defer conn.Close()
+
+	senderKP := keypair.MustRandom()
+	receiverKP := keypair.MustRandom()
+	issuerKP := keypair.MustRandom()
+	assetGOAT := txnbuild.CreditAsset{
+		Code:   "GOAT",
+		Issuer: issuerKP.Address(),
+	}
+	kycThresholdAmount, err := amount.ParseInt64("500")
+	require.NoError(t, err)
+
+	horizonMock := horizonclient.MockClient{}
+	horizonMock.
+		On("AccountDetail", horizonclient.AccountRequest{AccountID: senderKP.Address()}).
+		Return(horizon.Account{
+			AccountID: senderKP.Address(),
+			Sequence:  2,
+		}, nil)
+
+	handler := txApproveHandler{
+		issuerKP:          issuerKP,
+		assetCode:         assetGOAT.GetCode(),
+		horizonClient:     &horizonMock,
+		networkPassphrase: network.TestNetworkPassphrase,
+		db:                conn,
+		kycThreshold:      kycThresholdAmount,
+		baseURL:           "https://example.com",
+	}
+
+	tx, err := txnbuild.NewTransaction(
+		txnbuild.TransactionParams{
+			SourceAccount: &horizon.Account{
+				AccountID: senderKP.Address(),
+				Sequence:  2,
+			},
+			IncrementSequenceNum: true,
+			Operations: []txnbuild.Operation{
+				&txnbuild.AllowTrust{
+					Trustor:       senderKP.Address(),
+					Type:          assetGOAT,
+					Authorize:     true,
+					SourceAccount: issuerKP.Address(),
+				},
+				&txnbuild.AllowTrust{
+					Trustor:       receiverKP.Address(),
+					Type:          assetGOAT,
+					Authorize:     true,
+					SourceAccount: issuerKP.Address(),
+				},
+				&txnbuild.Payment{
+					SourceAccount: senderKP.Address(),
+					Destination:   receiverKP.Address(),
+					Amount:        "1",
+					Asset:         assetGOAT,
+				},
+				&txnbuild.AllowTrust{
+					Trustor:       receiverKP.Address(),
+					Type:          assetGOAT,
+					Authorize:     false,
+					SourceAccount: issuerKP.Address(),
+				},
+				&txnbuild.AllowTrust{
+					Trustor:       senderKP.Address(),
+					Type:          assetGOAT,
+					Authorize:     false,
+					SourceAccount: issuerKP.Address(),
+				},
+			},
+			BaseFee:       txnbuild.MinBaseFee,
+			Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+		},
+	)
+	require.NoError(t, err)
+	txe, err := tx.Base64()
+	require.NoError(t, err)
+
+	txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: txe})
+	require.NoError(t, err)
+	require.Equal(t, NewSuccessTxApprovalResponse(txApprovalResp.Tx, "Transaction is compliant and signed by the issuer."), txApprovalResp)
+}
+
+func TestTxApproveHandler_txApprove_actionRequired(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	defer db.Close()
+	conn := db.Open()
+	defer conn.Close()
+
+	senderKP := keypair.MustRandom()
+	receiverKP := keypair.MustRandom()
+	issuerKP := keypair.MustRandom()
+	assetGOAT := txnbuild.CreditAsset{
+		Code:   "GOAT",
+		Issuer: issuerKP.Address(),
+	}
+	kycThresholdAmount, err := amount.ParseInt64("500")
+	require.NoError(t, err)
+
+	horizonMock := horizonclient.MockClient{}
+	horizonMock.
+		On("AccountDetail", horizonclient.AccountRequest{AccountID: senderKP.Address()}).
+		Return(horizon.Account{
+			AccountID: senderKP.Address(),
+			Sequence:  2,
+		}, nil)
+
+	handler := txApproveHandler{
+		issuerKP:          issuerKP,
+		assetCode:         assetGOAT.GetCode(),
+		horizonClient:     &horizonMock,
+		networkPassphrase: network.TestNetworkPassphrase,
+		db:                conn,
+		kycThreshold:      kycThresholdAmount,
+		baseURL:           "https://example.com",
+	}
+
+	tx, err := txnbuild.NewTransaction(
+		txnbuild.TransactionParams{
+			SourceAccount: &horizon.Account{
+				AccountID: senderKP.Address(),
+				Sequence:  2,
+			},
+			IncrementSequenceNum: true,
+			Operations: []txnbuild.Operation{
+				&txnbuild.Payment{
+					Destination: receiverKP.Address(),
+					Amount:      "501",
+					Asset:       assetGOAT,
+				},
+			},
+			BaseFee:       txnbuild.MinBaseFee,
+			Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+		},
+	)
+	require.NoError(t, err)
+	txe, err := tx.Base64()
+	require.NoError(t, err)
+
+	txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: txe})
+	require.NoError(t, err)
+
+	var callbackID string
+	q := `SELECT callback_id FROM accounts_kyc_status WHERE stellar_address = $1`
+	err = conn.QueryRowContext(ctx, q, senderKP.Address()).Scan(&callbackID)
+	require.NoError(t, err)
+
+	wantResp := &txApprovalResponse{
+		Status:       sep8StatusActionRequired,
+		Message:      "Payments exceeding 500.00 GOAT require KYC approval. Please provide an email address.",
+		ActionMethod: "POST",
+		StatusCode:   http.StatusOK,
+		ActionURL:    "https://example.com/kyc-status/" + callbackID,
+		ActionFields: []string{"email_address"},
+	}
+	require.Equal(t, wantResp, txApprovalResp)
+}
+
+func TestTxApproveHandler_txApprove_revised(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	defer db.Close()
+	conn := db.Open()
+	defer conn.Close()
+
+	senderKP := keypair.MustRandom()
+	receiverKP := keypair.MustRandom()
+	issuerKP := keypair.MustRandom()
+	assetGOAT := txnbuild.CreditAsset{
+		Code:   "GOAT",
+		Issuer: issuerKP.Address(),
+	}
+	kycThresholdAmount, err := amount.ParseInt64("500")
+	require.NoError(t, err)
+
+	horizonMock := horizonclient.MockClient{}
+	horizonMock.
+		On("AccountDetail", horizonclient.AccountRequest{AccountID: senderKP.Address()}).
+		Return(horizon.Account{
+			AccountID: senderKP.Address(),
+			Sequence:  2,
+		}, nil)
+	horizonMock.
+		On("Assets", horizonclient.AssetRequest{
+			ForAssetCode:   assetGOAT.GetCode(),
+			ForAssetIssuer: issuerKP.Address(),
+			Limit:          1,
+		}).
+		Return(horizon.AssetsPage{
+			Embedded: struct{ Records []horizon.AssetStat }{
+				Records: []horizon.AssetStat{
+					{Flags: horizon.AccountFlags{AuthRequired: true, AuthRevocable: true}},
+				},
+			},
+		}, nil)
+
+	handler := txApproveHandler{
+		issuerKP:          issuerKP,
+		assetCode:         assetGOAT.GetCode(),
+		horizonClient:     &horizonMock,
+		networkPassphrase: network.TestNetworkPassphrase,
+		db:                conn,
+		kycThreshold:      kycThresholdAmount,
+		baseURL:           "https://example.com",
+	}
+
+	tx, err := txnbuild.NewTransaction(
+		txnbuild.TransactionParams{
+			SourceAccount: &horizon.Account{
+				AccountID: senderKP.Address(),
+				Sequence:  2,
+			},
+			IncrementSequenceNum: true,
+			Operations: []txnbuild.Operation{
+				&txnbuild.Payment{
+					Destination: receiverKP.Address(),
+					Amount:      "500",
+					Asset:       assetGOAT,
+				},
+			},
+			BaseFee:       txnbuild.MinBaseFee,
+			Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+		},
+	)
+	require.NoError(t, err)
+	txe, err := tx.Base64()
+	require.NoError(t, err)
+
+	txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: txe})
+	require.NoError(t, err)
+	require.Equal(t, sep8StatusRevised, txApprovalResp.Status)
+	require.Equal(t, http.StatusOK, txApprovalResp.StatusCode)
+	require.Equal(t, "Authorization and deauthorization operations were added.", txApprovalResp.Message)
+
+	gotGenericTx, err := txnbuild.TransactionFromXDR(txApprovalResp.Tx)
+	require.NoError(t, err)
+	gotTx, ok := gotGenericTx.Transaction()
+	require.True(t, ok)
+	require.Equal(t, senderKP.Address(), gotTx.SourceAccount().AccountID)
+	require.Equal(t, int64(3), gotTx.SourceAccount().Sequence)
+
+	require.Len(t, gotTx.Operations(), 5)
+	// AllowTrust op where issuer fully authorizes sender, asset GOAT
+	op0, ok := gotTx.Operations()[0].(*txnbuild.AllowTrust)
+	require.True(t, ok)
+	assert.Equal(t, op0.Trustor, senderKP.Address())
+	assert.Equal(t, op0.Type.GetCode(), assetGOAT.GetCode())
+	require.True(t, op0.Authorize)
+	// AllowTrust op where issuer fully authorizes receiver, asset GOAT
+	op1, ok := gotTx.Operations()[1].(*txnbuild.AllowTrust)
+	require.True(t, ok)
+	assert.Equal(t, op1.Trustor, receiverKP.Address())
+	assert.Equal(t, op1.Type.GetCode(), assetGOAT.GetCode())
+	require.True(t, op1.Authorize)
+	// Payment from sender to receiver
+	op2, ok := gotTx.Operations()[2].(*txnbuild.Payment)
+	require.True(t, ok)
+	assert.Equal(t, op2.Destination, receiverKP.Address())
+	assert.Equal(t, op2.Asset, assetGOAT)
+	// AllowTrust op where issuer fully deauthorizes receiver, asset GOAT
+	op3, ok := gotTx.Operations()[3].(*txnbuild.AllowTrust)
+	require.True(t, ok)
+	assert.Equal(t, op3.Trustor, receiverKP.Address())
+	assert.Equal(t, op3.Type.GetCode(), assetGOAT.GetCode())
+	require.False(t, op3.Authorize)
+	// AllowTrust op where issuer fully deauthorizes sender, asset GOAT
+	op4, ok := gotTx.Operations()[4].(*txnbuild.AllowTrust)
+	require.True(t, ok)
+	assert.Equal(t, op4.Trustor, senderKP.Address())
+	assert.Equal(t, op4.Type.GetCode(), assetGOAT.GetCode())
+	require.False(t, op4.Authorize)
+}
+
+func TestTxApproveHandler_txApprove_revisedWithinMaxTimeout(t *testing.T) {
+	ctx := context.Background()
+	senderKP := keypair.MustRandom()
+	receiverKP := keypair.MustRandom()
+	issuerKP := keypair.MustRandom()
+	assetGOAT := txnbuild.CreditAsset{
+		Code:   "GOAT",
+		Issuer: issuerKP.Address(),
+	}
+	kycThresholdAmount, err := amount.ParseInt64("500")
+	require.NoError(t, err)
+
+	horizonMock := horizonclient.MockClient{}
+	horizonMock.
+		On("AccountDetail", horizonclient.AccountRequest{AccountID: senderKP.Address()}).
+		Return(horizon.Account{
+			AccountID: senderKP.Address(),
+			Sequence:  2,
+		}, nil)
+	horizonMock.
+		On("Assets", horizonclient.AssetRequest{ForAssetCode: "GOAT", ForAssetIssuer: issuerKP.Address(), Limit: 1}).
+		Return(horizon.AssetsPage{}, nil)
+
+	handler := txApproveHandler{
+		issuerKP:          issuerKP,
+		assetCode:         assetGOAT.GetCode(),
+		horizonClient:     &horizonMock,
+		networkPassphrase: network.TestNetworkPassphrase,
+		kycThreshold:      kycThresholdAmount,
+		baseURL:           "https://example.com",
+		maxTimeout:        time.Minute,
+		requireMinTime:    true,
+	}
+
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &horizon.Account{
+			AccountID: senderKP.Address(),
+			Sequence:  2,
+		},
+		IncrementSequenceNum: true,
+		Operations: []txnbuild.Operation{
+			&txnbuild.Payment{
+				Destination: receiverKP.Address(),
+				Amount:      "1",
+				Asset:       assetGOAT,
+			},
+		},
+		BaseFee:       txnbuild.MinBaseFee,
+		Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewTimebounds(time.Now().Unix(), time.Now().Add(30*time.Second).Unix())},
+	})
+	require.NoError(t, err)
+	txe, err := tx.Base64()
+	require.NoError(t, err)
+
+	txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: txe})
+	require.NoError(t, err)
+	require.Equal(t, sep8StatusRevised, txApprovalResp.Status)
+
+	// the revised transaction fits the time bounds enforced by the server
+	genericTx, err := txnbuild.TransactionFromXDR(txApprovalResp.Tx)
+	require.NoError(t, err)
+	revisedTx, ok := genericTx.Transaction()
+	require.True(t, ok)
+	assert.NotZero(t, revisedTx.Timebounds().MinTime)
+	assert.LessOrEqual(t, revisedTx.Timebounds().MaxTime, time.Now().Add(time.Minute).Unix())
+
+	// so it's approved when submitted back to the server
+	txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: txApprovalResp.Tx})
+	require.NoError(t, err)
+	assert.Equal(t, sep8StatusSuccess, txApprovalResp.Status, txApprovalResp.Error)
+}
+
+func TestTxApproveHandler_txApprove_authNotRequired(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	defer db.Close()
+	conn := db.Open()
+	defer conn.Close()
+
+	senderKP := keypair.MustRandom()
+	receiverKP := keypair.MustRandom()
+	issuerKP := keypair.MustRandom()
+	assetGOAT := txnbuild.CreditAsset{
+		Code:   "GOAT",
+		Issuer: issuerKP.Address(),
+	}
+	kycThresholdAmount, err := amount.ParseInt64("500")
+	require.NoError(t, err)
+
+	horizonMock := horizonclient.MockClient{}
+	horizonMock.
+		On("AccountDetail", horizonclient.AccountRequest{AccountID: senderKP.Address()}).
+		Return(horizon.Account{
+			AccountID: senderKP.Address(),
+			Sequence:  2,
+		}, nil)
+	horizonMock.
+		On("Assets", horizonclient.AssetRequest{
+			ForAssetCode:   assetGOAT.GetCode(),
+			ForAssetIssuer: issuerKP.Address(),
+			Limit:          1,
+		}).
+		Return(horizon.AssetsPage{
+			Embedded: struct{ Records []horizon.AssetStat }{
+				Records: []horizon.AssetStat{
+					{Flags: horizon.AccountFlags{AuthRequired: false}},
+				},
+			},
+		}, nil)
+
+	handler := txApproveHandler{
+		issuerKP:          issuerKP,
+		assetCode:         assetGOAT.GetCode(),
+		horizonClient:     &horizonMock,
+		networkPassphrase: network.TestNetworkPassphrase,
+		db:                conn,
+		kycThreshold:      kycThresholdAmount,
+		baseURL:           "https://example.com",
+	}
+
+	tx, err := txnbuild.NewTransaction(
+		txnbuild.TransactionParams{
+			SourceAccount: &horizon.Account{
+				AccountID: senderKP.Address(),
+				Sequence:  2,
+			},
+			IncrementSequenceNum: true,
+			Operations: []txnbuild.Operation{
+				&txnbuild.Payment{
+					Destination: receiverKP.Address(),
+					Amount:      "500",
+					Asset:       assetGOAT,
+				},
+			},
+			BaseFee:       txnbuild.MinBaseFee,
+			Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewTimeout(60)},
+		},
+	)
+	require.NoError(t, err)
+	txe, err := tx.Base64()
+	require.NoError(t, err)
+
+	txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: txe})
+	require.NoError(t, err)
+	require.Equal(t, NewSuccessTxApprovalResponse(txApprovalResp.Tx, "Transaction is compliant and signed by the issuer."), txApprovalResp)
+
+	gotGenericTx, err := txnbuild.TransactionFromXDR(txApprovalResp.Tx)
+	require.NoError(t, err)
+	gotTx, ok := gotGenericTx.Transaction()
+	require.True(t, ok)
+	require.Equal(t, senderKP.Address(), gotTx.SourceAccount().AccountID)
+	require.Equal(t, int64(3), gotTx.SourceAccount().Sequence)
+
+	// the original payment is signed without the AllowTrust operations
+	require.Len(t, gotTx.Operations(), 1)
+	op0, ok := gotTx.Operations()[0].(*txnbuild.Payment)
+	require.True(t, ok)
+	assert.Equal(t, op0.Destination, receiverKP.Address())
+	assert.Equal(t, op0.Asset, assetGOAT)
+
+	txHash, err := gotTx.Hash(handler.networkPassphrase)
+	require.NoError(t, err)
+	require.Len(t, gotTx.Signatures(), 1)
+	err = issuerKP.Verify(txHash[:], gotTx.Signatures()[0].Signature)
+	require.NoError(t, err)
+
+	// transactions valid for longer than a revised transaction are rejected
+	for _, timeBounds := range []txnbuild.TimeBounds{
+		txnbuild.NewInfiniteTimeout(),
+		txnbuild.NewTimeout(int64(defaultRevisedTxTimeout/time.Second) + 60),
+	} {
+		tx, err = txnbuild.NewTransaction(
+			txnbuild.TransactionParams{
+				SourceAccount: &horizon.Account{
+					AccountID: senderKP.Address(),
+					Sequence:  2,
+				},
+				IncrementSequenceNum: true,
+				Operations: []txnbuild.Operation{
+					&txnbuild.Payment{
+						Destination: receiverKP.Address(),
+						Amount:      "500",
+						Asset:       assetGOAT,
+					},
+				},
+				BaseFee:       txnbuild.MinBaseFee,
+				Preconditions: txnbuild.Preconditions{TimeBounds: timeBounds},
+			},
+		)
+		require.NoError(t, err)
+		txe, err = tx.Base64()
+		require.NoError(t, err)
+
+		txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: txe})
+		require.NoError(t, err)
+		assert.Equal(t, NewRejectedTxApprovalResponse("Transaction max time can't be more than 300 seconds in the future."), txApprovalResp)
+	}
+}
+
+func TestTxApproveHandler_txApprove_pathPayments(t *testing.T) {
+	ctx := context.Background()
+	senderKP := keypair.MustRandom()
+	receiverKP := keypair.MustRandom()
+	issuerKP := keypair.MustRandom()
+	assetGOAT := txnbuild.CreditAsset{
+		Code:   "GOAT",
+		Issuer: issuerKP.Address(),
+	}
+	kycThresholdAmount, err := amount.ParseInt64("500")
+	require.NoError(t, err)
+
+	horizonMock := horizonclient.MockClient{}
+	horizonMock.
+		On("AccountDetail", horizonclient.AccountRequest{AccountID: senderKP.Address()}).
+		Return(horizon.Account{
+			AccountID: senderKP.Address(),
+			Sequence:  2,
+		}, nil)
+	horizonMock.
+		On("Assets", horizonclient.AssetRequest{
+			ForAssetCode:   assetGOAT.GetCode(),
+			ForAssetIssuer: issuerKP.Address(),
+			Limit:          1,
+		}).
+		Return(horizon.AssetsPage{
+			Embedded: struct{ Records []horizon.AssetStat }{
+				Records: []horizon.AssetStat{
+					{Flags: horizon.AccountFlags{AuthRequired: true, AuthRevocable: true}},
+				},
+			},
+		}, nil)
+
+	// amounts up to the KYC threshold don't need the database
+	handler := txApproveHandler{
+		issuerKP:          issuerKP,
+		assetCode:         assetGOAT.GetCode(),
+		horizonClient:     &horizonMock,
+		networkPassphrase: network.TestNetworkPassphrase,
+		kycThreshold:      kycThresholdAmount,
+		baseURL:           "https://example.com",
+	}
+
+	buildTx := func(operations ...txnbuild.Operation) string {
+		tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+			SourceAccount: &horizon.Account{
+				AccountID: senderKP.Address(),
+				Sequence:  2,
+			},
+			IncrementSequenceNum: true,
+			Operations:           operations,
+			BaseFee:              txnbuild.MinBaseFee,
+			Preconditions:        txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+		})
+		require.NoError(t, err)
+		txe, err := tx.Base64()
+		require.NoError(t, err)
+		return txe
+	}
+	allowTrust := func(trustor string, authorize bool) *txnbuild.AllowTrust {
+		return &txnbuild.AllowTrust{
+			Trustor:       trustor,
+			Type:          assetGOAT,
+			Authorize:     authorize,
+			SourceAccount: issuerKP.Address(),
+		}
+	}
+	requireOperations := func(txe string, want ...txnbuild.Operation) {
+		gotGenericTx, err := txnbuild.TransactionFromXDR(txe)
+		require.NoError(t, err)
+		gotTx, ok := gotGenericTx.Transaction()
+		require.True(t, ok)
+		got := gotTx.Operations()
+		require.Len(t, got, len(want))
+		// the payment operation is decoded from XDR, so it's only compared by
+		// type and destination
+		for i, op := range want {
+			if _, ok := op.(*txnbuild.AllowTrust); !ok {
+				require.IsType(t, op, got[i])
+				require.Equal(t, paymentDestination(op), paymentDestination(got[i]))
+				want[i] = got[i]
+			}
+		}
+		require.True(t, operationsMatch(got, want), "unexpected operations %+v", got)
+	}
+
+	// sending the regulated asset through a strict send path payment only
+	// authorizes the sender
+	strictSendOp := &txnbuild.PathPaymentStrictSend{
+		SendAsset:   assetGOAT,
+		SendAmount:  "500",
+		Destination: receiverKP.Address(),
+		DestAsset:   txnbuild.NativeAsset{},
+		DestMin:     "1",
+	}
+	txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: buildTx(strictSendOp)})
+	require.NoError(t, err)
+	require.Equal(t, sep8StatusRevised, txApprovalResp.Status)
+	requireOperations(txApprovalResp.Tx, allowTrust(senderKP.Address(), true), strictSendOp, allowTrust(senderKP.Address(), false))
+
+	// receiving the regulated asset through a strict receive path payment only
+	// authorizes the receiver
+	strictReceiveOp := &txnbuild.PathPaymentStrictReceive{
+		SendAsset:   txnbuild.NativeAsset{},
+		SendMax:     "1000",
+		Destination: receiverKP.Address(),
+		DestAsset:   assetGOAT,
+		DestAmount:  "500",
+	}
+	txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: buildTx(strictReceiveOp)})
+	require.NoError(t, err)
+	require.Equal(t, sep8StatusRevised, txApprovalResp.Status)
+	requireOperations(txApprovalResp.Tx, allowTrust(receiverKP.Address(), true), strictReceiveOp, allowTrust(receiverKP.Address(), false))
+
+	// path payments of the regulated asset to itself authorize both accounts
+	bothOp := &txnbuild.PathPaymentStrictReceive{
+		SendAsset:   assetGOAT,
+		SendMax:     "500",
+		Destination: receiverKP.Address(),
+		DestAsset:   assetGOAT,
+		DestAmount:  "400",
+		Path:        []txnbuild.Asset{txnbuild.NativeAsset{}},
+	}
+	txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: buildTx(bothOp)})
+	require.NoError(t, err)
+	require.Equal(t, sep8StatusRevised, txApprovalResp.Status)
+	requireOperations(txApprovalResp.Tx,
+		allowTrust(senderKP.Address(), true),
+		allowTrust(receiverKP.Address(), true),
+		bothOp,
+		allowTrust(receiverKP.Address(), false),
+		allowTrust(senderKP.Address(), false),
+	)
+
+	// the amount received through a strict send path payment isn't bounded
+	strictSendReceiveOp := &txnbuild.PathPaymentStrictSend{
+		SendAsset:   txnbuild.NativeAsset{},
+		SendAmount:  "1",
+		Destination: receiverKP.Address(),
+		DestAsset:   assetGOAT,
+		DestMin:     "1",
+	}
+	txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: buildTx(strictSendReceiveOp)})
+	require.NoError(t, err)
+	assert.Equal(t, NewRejectedTxApprovalResponse("The payment asset is not supported by this issuer."), txApprovalResp)
+
+	// path payments not transferring the regulated asset are rejected
+	otherAssetOp := &txnbuild.PathPaymentStrictReceive{
+		SendAsset:   txnbuild.NativeAsset{},
+		SendMax:     "1",
+		Destination: receiverKP.Address(),
+		DestAsset:   txnbuild.CreditAsset{Code: "GOAT", Issuer: keypair.MustRandom().Address()},
+		DestAmount:  "1",
+	}
+	txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: buildTx(otherAssetOp)})
+	require.NoError(t, err)
+	assert.Equal(t, NewRejectedTxApprovalResponse("The payment asset is not supported by this issuer."), txApprovalResp)
+
+	// a compliant sandwiched path payment is signed
+	txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: buildTx(
+		allowTrust(senderKP.Address(), true),
+		strictSendOp,
+		allowTrust(senderKP.Address(), false),
+	)})
 	require.NoError(t, err)
+	require.Equal(t, sep8StatusSuccess, txApprovalResp.Status)
+	requireOperations(txApprovalResp.Tx, allowTrust(senderKP.Address(), true), strictSendOp, allowTrust(senderKP.Address(), false))
 
-	txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: txe})
+	// a sandwich not matching the path payment is rejected
+	txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: buildTx(
+		allowTrust(receiverKP.Address(), true),
+		strictSendOp,
+		allowTrust(receiverKP.Address(), false),
+	)})
 	require.NoError(t, err)
-	assert.Equal(t, NewRejectedTxApprovalResponse("Invalid transaction sequence number."), txApprovalResp)
+	assert.Equal(t, NewRejectedTxApprovalResponse("There are one or more unexpected operations in the provided transaction."), txApprovalResp)
 }
 
-func TestTxApproveHandler_txApprove_success(t *testing.T) {
+func TestTxApproveHandler_txApprove_alphaNum12(t *testing.T) {
 	ctx := context.Background()
-	db := dbtest.Open(t)
-	defer db.Close()
-	conn := db.Open()
-	defer conn.Close()
-
 	senderKP := keypair.MustRandom()
 	receiverKP := keypair.MustRandom()
 	issuerKP := keypair.MustRandom()
 	assetGOAT := txnbuild.CreditAsset{
-		Code:   "GOAT",
+		Code:   "GOATGOATGOAT",
 		Issuer: issuerKP.Address(),
 	}
 	kycThresholdAmount, err := amount.ParseInt64("500")
@@ -518,76 +1419,142 @@ func TestTxApproveHandler_txApprove_success(t *testing.T) {
 			AccountID: senderKP.Address(),
 			Sequence:  2,
 		}, nil)
+	horizonMock.
+		On("Assets", horizonclient.AssetRequest{
+			ForAssetCode:   assetGOAT.GetCode(),
+			ForAssetIssuer: issuerKP.Address(),
+			Limit:          1,
+		}).
+		Return(horizon.AssetsPage{
+			Embedded: struct{ Records []horizon.AssetStat }{
+				Records: []horizon.AssetStat{
+					{Flags: horizon.AccountFlags{AuthRequired: true, AuthRevocable: true}},
+				},
+			},
+		}, nil)
 
+	// amounts up to the KYC threshold don't need the database
 	handler := txApproveHandler{
 		issuerKP:          issuerKP,
 		assetCode:         assetGOAT.GetCode(),
 		horizonClient:     &horizonMock,
 		networkPassphrase: network.TestNetworkPassphrase,
-		db:                conn,
 		kycThreshold:      kycThresholdAmount,
 		baseURL:           "https://example.com",
 	}
 
-	tx, err := txnbuild.NewTransaction(
-		txnbuild.TransactionParams{
+	buildTx := func(operations ...txnbuild.Operation) string {
+		tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
 			SourceAccount: &horizon.Account{
 				AccountID: senderKP.Address(),
 				Sequence:  2,
 			},
 			IncrementSequenceNum: true,
+			Operations:           operations,
+			BaseFee:              txnbuild.MinBaseFee,
+			Preconditions:        txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+		})
+		require.NoError(t, err)
+		txe, err := tx.Base64()
+		require.NoError(t, err)
+		return txe
+	}
+
+	// a payment of the 12-character asset is revised
+	paymentOp := &txnbuild.Payment{
+		Destination: receiverKP.Address(),
+		Amount:      "1",
+		Asset:       assetGOAT,
+	}
+	txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: buildTx(paymentOp)})
+	require.NoError(t, err)
+	require.Equal(t, sep8StatusRevised, txApprovalResp.Status)
+
+	// the revised transaction is compliant and gets signed
+	gotGenericTx, err := txnbuild.TransactionFromXDR(txApprovalResp.Tx)
+	require.NoError(t, err)
+	gotTx, ok := gotGenericTx.Transaction()
+	require.True(t, ok)
+	require.Len(t, gotTx.Operations(), 5)
+	txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: buildTx(gotTx.Operations()...)})
+	require.NoError(t, err)
+	require.Equal(t, sep8StatusSuccess, txApprovalResp.Status)
+	require.Equal(t, "Transaction is compliant and signed by the issuer.", txApprovalResp.Message)
+
+	// a compliant transaction authorizing another asset code is rejected
+	allowTrust := func(trustor string, authorize bool, code string) *txnbuild.AllowTrust {
+		return &txnbuild.AllowTrust{
+			Trustor:       trustor,
+			Type:          txnbuild.CreditAsset{Code: code, Issuer: issuerKP.Address()},
+			Authorize:     authorize,
+			SourceAccount: issuerKP.Address(),
+		}
+	}
+	txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: buildTx(
+		allowTrust(senderKP.Address(), true, "GOATGOATGOA"),
+		allowTrust(receiverKP.Address(), true, "GOATGOATGOA"),
+		paymentOp,
+		allowTrust(receiverKP.Address(), false, "GOATGOATGOA"),
+		allowTrust(senderKP.Address(), false, "GOATGOATGOA"),
+	)})
+	require.NoError(t, err)
+	require.Equal(t, NewRejectedTxApprovalResponse("There are one or more unexpected operations in the provided transaction."), txApprovalResp)
+}
+
+func TestTxApproveHandler_txApprove_rateLimited(t *testing.T) {
+	ctx := context.Background()
+	issuerKP := keypair.MustRandom()
+	handler := txApproveHandler{
+		issuerKP:    issuerKP,
+		assetCode:   "FOO",
+		rateLimiter: newAccountRateLimiter(2),
+	}
+
+	buildTx := func(sourceKP *keypair.Full) string {
+		tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+			SourceAccount: &horizon.Account{
+				AccountID: sourceKP.Address(),
+				Sequence:  2,
+			},
+			IncrementSequenceNum: true,
 			Operations: []txnbuild.Operation{
-				&txnbuild.AllowTrust{
-					Trustor:       senderKP.Address(),
-					Type:          assetGOAT,
-					Authorize:     true,
-					SourceAccount: issuerKP.Address(),
-				},
-				&txnbuild.AllowTrust{
-					Trustor:       receiverKP.Address(),
-					Type:          assetGOAT,
-					Authorize:     true,
-					SourceAccount: issuerKP.Address(),
-				},
 				&txnbuild.Payment{
-					SourceAccount: senderKP.Address(),
-					Destination:   receiverKP.Address(),
-					Amount:        "1",
-					Asset:         assetGOAT,
-				},
-				&txnbuild.AllowTrust{
-					Trustor:       receiverKP.Address(),
-					Type:          assetGOAT,
-					Authorize:     false,
-					SourceAccount: issuerKP.Address(),
-				},
-				&txnbuild.AllowTrust{
-					Trustor:       senderKP.Address(),
-					Type:          assetGOAT,
-					Authorize:     false,
-					SourceAccount: issuerKP.Address(),
+					Destination: keypair.MustRandom().Address(),
+					Amount:      "1",
+					Asset:       txnbuild.NativeAsset{},
 				},
 			},
 			BaseFee:       txnbuild.MinBaseFee,
 			Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
-		},
-	)
-	require.NoError(t, err)
-	txe, err := tx.Base64()
+		})
+		require.NoError(t, err)
+		txe, err := tx.Base64()
+		require.NoError(t, err)
+		return txe
+	}
+
+	// requests within the limit go through the validation
+	senderKP := keypair.MustRandom()
+	for i := 0; i < 2; i++ {
+		txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: buildTx(senderKP)})
+		require.NoError(t, err)
+		require.Equal(t, NewRejectedTxApprovalResponse("The payment asset is not supported by this issuer."), txApprovalResp)
+	}
+
+	// the next request of the same source account is throttled
+	txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: buildTx(senderKP)})
 	require.NoError(t, err)
+	require.Equal(t, sep8StatusRejected, txApprovalResp.Status)
+	require.Regexp(t, `^Too many requests for this source account, please retry in \d+ seconds\.$`, txApprovalResp.Error)
 
-	txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: txe})
+	// other source accounts are unaffected
+	txApprovalResp, err = handler.txApprove(ctx, txApproveRequest{Tx: buildTx(keypair.MustRandom())})
 	require.NoError(t, err)
-	require.Equal(t, NewSuccessTxApprovalResponse(txApprovalResp.Tx, "Transaction is compliant and signed by the issuer."), txApprovalResp)
+	require.Equal(t, NewRejectedTxApprovalResponse("The payment asset is not supported by this issuer."), txApprovalResp)
 }
 
-func TestTxApproveHandler_txApprove_actionRequired(t *testing.T) {
+func TestTxApproveHandler_txApprove_idempotent(t *testing.T) {
 	ctx := context.Background()
-	db := dbtest.Open(t)
-	defer db.Close()
-	conn := db.Open()
-	defer conn.Close()
-
 	senderKP := keypair.MustRandom()
 	receiverKP := keypair.MustRandom()
 	issuerKP := keypair.MustRandom()
@@ -598,74 +1565,71 @@ func TestTxApproveHandler_txApprove_actionRequired(t *testing.T) {
 	kycThresholdAmount, err := amount.ParseInt64("500")
 	require.NoError(t, err)
 
+	// the account is only looked up once
 	horizonMock := horizonclient.MockClient{}
 	horizonMock.
 		On("AccountDetail", horizonclient.AccountRequest{AccountID: senderKP.Address()}).
 		Return(horizon.Account{
 			AccountID: senderKP.Address(),
 			Sequence:  2,
-		}, nil)
+		}, nil).
+		Once()
 
 	handler := txApproveHandler{
 		issuerKP:          issuerKP,
 		assetCode:         assetGOAT.GetCode(),
 		horizonClient:     &horizonMock,
 		networkPassphrase: network.TestNetworkPassphrase,
-		db:                conn,
 		kycThreshold:      kycThresholdAmount,
 		baseURL:           "https://example.com",
+		responseCache:     newTxApprovalCache(time.Minute),
 	}
 
-	tx, err := txnbuild.NewTransaction(
-		txnbuild.TransactionParams{
-			SourceAccount: &horizon.Account{
-				AccountID: senderKP.Address(),
-				Sequence:  2,
-			},
-			IncrementSequenceNum: true,
-			Operations: []txnbuild.Operation{
-				&txnbuild.Payment{
-					Destination: receiverKP.Address(),
-					Amount:      "501",
-					Asset:       assetGOAT,
-				},
+	allowTrust := func(trustor string, authorize bool) *txnbuild.AllowTrust {
+		return &txnbuild.AllowTrust{
+			Trustor:       trustor,
+			Type:          assetGOAT,
+			Authorize:     authorize,
+			SourceAccount: issuerKP.Address(),
+		}
+	}
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &horizon.Account{
+			AccountID: senderKP.Address(),
+			Sequence:  2,
+		},
+		IncrementSequenceNum: true,
+		Operations: []txnbuild.Operation{
+			allowTrust(senderKP.Address(), true),
+			allowTrust(receiverKP.Address(), true),
+			&txnbuild.Payment{
+				Destination: receiverKP.Address(),
+				Amount:      "1",
+				Asset:       assetGOAT,
 			},
-			BaseFee:       txnbuild.MinBaseFee,
-			Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+			allowTrust(receiverKP.Address(), false),
+			allowTrust(senderKP.Address(), false),
 		},
-	)
+		BaseFee:       txnbuild.MinBaseFee,
+		Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+	})
 	require.NoError(t, err)
 	txe, err := tx.Base64()
 	require.NoError(t, err)
 
-	txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: txe})
+	firstResp, err := handler.txApprove(ctx, txApproveRequest{Tx: txe})
 	require.NoError(t, err)
+	require.Equal(t, sep8StatusSuccess, firstResp.Status)
 
-	var callbackID string
-	q := `SELECT callback_id FROM accounts_kyc_status WHERE stellar_address = $1`
-	err = conn.QueryRowContext(ctx, q, senderKP.Address()).Scan(&callbackID)
+	secondResp, err := handler.txApprove(ctx, txApproveRequest{Tx: txe})
 	require.NoError(t, err)
-
-	wantResp := &txApprovalResponse{
-		Status:       sep8StatusActionRequired,
-		Message:      "Payments exceeding 500.00 GOAT require KYC approval. Please provide an email address.",
-		ActionMethod: "POST",
-		StatusCode:   http.StatusOK,
-		ActionURL:    "https://example.com/kyc-status/" + callbackID,
-		ActionFields: []string{"email_address"},
-	}
-	require.Equal(t, wantResp, txApprovalResp)
+	require.Equal(t, firstResp, secondResp)
+	horizonMock.AssertExpectations(t)
 }
 
-func TestTxApproveHandler_txApprove_revised(t *testing.T) {
+func TestTxApproveHandler_txApprove_revisedNotCached(t *testing.T) {
 	ctx := context.Background()
-	db := dbtest.Open(t)
-	defer db.Close()
-	conn := db.Open()
-	defer conn.Close()
-
 	senderKP := keypair.MustRandom()
-	receiverKP := keypair.MustRandom()
 	issuerKP := keypair.MustRandom()
 	assetGOAT := txnbuild.CreditAsset{
 		Code:   "GOAT",
@@ -674,89 +1638,179 @@ func TestTxApproveHandler_txApprove_revised(t *testing.T) {
 	kycThresholdAmount, err := amount.ParseInt64("500")
 	require.NoError(t, err)
 
+	// revisions are evaluated again on every submission
 	horizonMock := horizonclient.MockClient{}
 	horizonMock.
 		On("AccountDetail", horizonclient.AccountRequest{AccountID: senderKP.Address()}).
 		Return(horizon.Account{
 			AccountID: senderKP.Address(),
 			Sequence:  2,
-		}, nil)
+		}, nil).
+		Twice()
+	horizonMock.
+		On("Assets", horizonclient.AssetRequest{ForAssetCode: "GOAT", ForAssetIssuer: issuerKP.Address(), Limit: 1}).
+		Return(horizon.AssetsPage{}, nil).
+		Twice()
 
 	handler := txApproveHandler{
 		issuerKP:          issuerKP,
 		assetCode:         assetGOAT.GetCode(),
 		horizonClient:     &horizonMock,
 		networkPassphrase: network.TestNetworkPassphrase,
-		db:                conn,
 		kycThreshold:      kycThresholdAmount,
 		baseURL:           "https://example.com",
+		responseCache:     newTxApprovalCache(time.Minute),
 	}
 
-	tx, err := txnbuild.NewTransaction(
-		txnbuild.TransactionParams{
-			SourceAccount: &horizon.Account{
-				AccountID: senderKP.Address(),
-				Sequence:  2,
-			},
-			IncrementSequenceNum: true,
-			Operations: []txnbuild.Operation{
-				&txnbuild.Payment{
-					Destination: receiverKP.Address(),
-					Amount:      "500",
-					Asset:       assetGOAT,
-				},
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &horizon.Account{
+			AccountID: senderKP.Address(),
+			Sequence:  2,
+		},
+		IncrementSequenceNum: true,
+		Operations: []txnbuild.Operation{
+			&txnbuild.Payment{
+				Destination: keypair.MustRandom().Address(),
+				Amount:      "1",
+				Asset:       assetGOAT,
 			},
-			BaseFee:       txnbuild.MinBaseFee,
-			Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
 		},
-	)
+		BaseFee:       txnbuild.MinBaseFee,
+		Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+	})
 	require.NoError(t, err)
 	txe, err := tx.Base64()
 	require.NoError(t, err)
 
-	txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: txe})
+	for i := 0; i < 2; i++ {
+		txApprovalResp, err := handler.txApprove(ctx, txApproveRequest{Tx: txe})
+		require.NoError(t, err)
+		require.Equal(t, sep8StatusRevised, txApprovalResp.Status)
+	}
+	require.Empty(t, handler.responseCache.entries)
+	horizonMock.AssertExpectations(t)
+}
+
+func TestParseRegulatedPayment(t *testing.T) {
+	senderKP := keypair.MustRandom()
+	receiverKP := keypair.MustRandom()
+	assetGOAT := txnbuild.CreditAsset{Code: "GOAT", Issuer: keypair.MustRandom().Address()}
+	otherAsset := txnbuild.CreditAsset{Code: "GOAT", Issuer: keypair.MustRandom().Address()}
+
+	testCases := []struct {
+		name string
+		op   txnbuild.Operation
+		want *regulatedPayment
+	}{
+		{
+			name: "payment",
+			op:   &txnbuild.Payment{Destination: receiverKP.Address(), Amount: "10", Asset: assetGOAT},
+			want: &regulatedPayment{amount: "10", trustors: []string{senderKP.Address(), receiverKP.Address()}},
+		},
+		{
+			name: "payment of another asset",
+			op:   &txnbuild.Payment{Destination: receiverKP.Address(), Amount: "10", Asset: otherAsset},
+		},
+		{
+			name: "strict send sending the regulated asset",
+			op:   &txnbuild.PathPaymentStrictSend{SendAsset: assetGOAT, SendAmount: "10", Destination: receiverKP.Address(), DestAsset: txnbuild.NativeAsset{}, DestMin: "1"},
+			want: &regulatedPayment{amount: "10", trustors: []string{senderKP.Address()}},
+		},
+		{
+			name: "strict send only receiving the regulated asset",
+			op:   &txnbuild.PathPaymentStrictSend{SendAsset: txnbuild.NativeAsset{}, SendAmount: "10", Destination: receiverKP.Address(), DestAsset: assetGOAT, DestMin: "1"},
+		},
+		{
+			name: "strict receive sending the regulated asset",
+			op:   &txnbuild.PathPaymentStrictReceive{SendAsset: assetGOAT, SendMax: "20", Destination: receiverKP.Address(), DestAsset: otherAsset, DestAmount: "10"},
+			want: &regulatedPayment{amount: "20", trustors: []string{senderKP.Address()}},
+		},
+		{
+			name: "strict receive receiving the regulated asset",
+			op:   &txnbuild.PathPaymentStrictReceive{SendAsset: otherAsset, SendMax: "20", Destination: receiverKP.Address(), DestAsset: assetGOAT, DestAmount: "10"},
+			want: &regulatedPayment{amount: "10", trustors: []string{receiverKP.Address()}},
+		},
+		{
+			name: "strict receive sending and receiving the regulated asset",
+			op:   &txnbuild.PathPaymentStrictReceive{SendAsset: assetGOAT, SendMax: "20", Destination: receiverKP.Address(), DestAsset: assetGOAT, DestAmount: "10"},
+			want: &regulatedPayment{amount: "20", trustors: []string{senderKP.Address(), receiverKP.Address()}},
+		},
+		{
+			name: "not a payment",
+			op:   &txnbuild.BumpSequence{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, parseRegulatedPayment(tc.op, senderKP.Address(), assetGOAT))
+		})
+	}
+}
+
+func TestTxApproveHandler_isAuthorizationRequired(t *testing.T) {
+	issuerKP := keypair.MustRandom()
+	assetRequest := horizonclient.AssetRequest{
+		ForAssetCode:   "GOAT",
+		ForAssetIssuer: issuerKP.Address(),
+		Limit:          1,
+	}
+
+	// authorization is assumed to be required if the asset is unknown
+	horizonMock := horizonclient.MockClient{}
+	horizonMock.On("Assets", assetRequest).Return(horizon.AssetsPage{}, nil).Once()
+	h := txApproveHandler{
+		issuerKP:      issuerKP,
+		assetCode:     "GOAT",
+		horizonClient: &horizonMock,
+	}
+	authRequired, err := h.isAuthorizationRequired()
 	require.NoError(t, err)
-	require.Equal(t, sep8StatusRevised, txApprovalResp.Status)
-	require.Equal(t, http.StatusOK, txApprovalResp.StatusCode)
-	require.Equal(t, "Authorization and deauthorization operations were added.", txApprovalResp.Message)
+	assert.True(t, authRequired)
 
-	gotGenericTx, err := txnbuild.TransactionFromXDR(txApprovalResp.Tx)
+	// auth_required flag is set
+	assetsPage := horizon.AssetsPage{}
+	assetsPage.Embedded.Records = []horizon.AssetStat{{Flags: horizon.AccountFlags{AuthRequired: true}}}
+	horizonMock.On("Assets", assetRequest).Return(assetsPage, nil).Once()
+	authRequired, err = h.isAuthorizationRequired()
 	require.NoError(t, err)
-	gotTx, ok := gotGenericTx.Transaction()
-	require.True(t, ok)
-	require.Equal(t, senderKP.Address(), gotTx.SourceAccount().AccountID)
-	require.Equal(t, int64(3), gotTx.SourceAccount().Sequence)
+	assert.True(t, authRequired)
 
-	require.Len(t, gotTx.Operations(), 5)
-	// AllowTrust op where issuer fully authorizes sender, asset GOAT
-	op0, ok := gotTx.Operations()[0].(*txnbuild.AllowTrust)
-	require.True(t, ok)
-	assert.Equal(t, op0.Trustor, senderKP.Address())
-	assert.Equal(t, op0.Type.GetCode(), assetGOAT.GetCode())
-	require.True(t, op0.Authorize)
-	// AllowTrust op where issuer fully authorizes receiver, asset GOAT
-	op1, ok := gotTx.Operations()[1].(*txnbuild.AllowTrust)
-	require.True(t, ok)
-	assert.Equal(t, op1.Trustor, receiverKP.Address())
-	assert.Equal(t, op1.Type.GetCode(), assetGOAT.GetCode())
-	require.True(t, op1.Authorize)
-	// Payment from sender to receiver
-	op2, ok := gotTx.Operations()[2].(*txnbuild.Payment)
-	require.True(t, ok)
-	assert.Equal(t, op2.Destination, receiverKP.Address())
-	assert.Equal(t, op2.Asset, assetGOAT)
-	// AllowTrust op where issuer fully deauthorizes receiver, asset GOAT
-	op3, ok := gotTx.Operations()[3].(*txnbuild.AllowTrust)
-	require.True(t, ok)
-	assert.Equal(t, op3.Trustor, receiverKP.Address())
-	assert.Equal(t, op3.Type.GetCode(), assetGOAT.GetCode())
-	require.False(t, op3.Authorize)
-	// AllowTrust op where issuer fully deauthorizes sender, asset GOAT
-	op4, ok := gotTx.Operations()[4].(*txnbuild.AllowTrust)
-	require.True(t, ok)
-	assert.Equal(t, op4.Trustor, senderKP.Address())
-	assert.Equal(t, op4.Type.GetCode(), assetGOAT.GetCode())
-	require.False(t, op4.Authorize)
+	// auth_required flag is not set
+	assetsPage.Embedded.Records = []horizon.AssetStat{{Flags: horizon.AccountFlags{AuthRevocable: true}}}
+	horizonMock.On("Assets", assetRequest).Return(assetsPage, nil).Once()
+	authRequired, err = h.isAuthorizationRequired()
+	require.NoError(t, err)
+	assert.False(t, authRequired)
+
+	horizonMock.AssertExpectations(t)
+}
+
+func TestTxApproveHandler_isAuthorizationRequired_cached(t *testing.T) {
+	issuerKP := keypair.MustRandom()
+	assetRequest := horizonclient.AssetRequest{
+		ForAssetCode:   "GOAT",
+		ForAssetIssuer: issuerKP.Address(),
+		Limit:          1,
+	}
+
+	// the asset is only looked up once
+	assetsPage := horizon.AssetsPage{}
+	assetsPage.Embedded.Records = []horizon.AssetStat{{Flags: horizon.AccountFlags{AuthRevocable: true}}}
+	horizonMock := horizonclient.MockClient{}
+	horizonMock.On("Assets", assetRequest).Return(assetsPage, nil).Once()
+	h := txApproveHandler{
+		issuerKP:          issuerKP,
+		assetCode:         "GOAT",
+		horizonClient:     &horizonMock,
+		authRequiredCache: newAuthRequiredCache(time.Minute),
+	}
+	for i := 0; i < 2; i++ {
+		authRequired, err := h.isAuthorizationRequired()
+		require.NoError(t, err)
+		assert.False(t, authRequired)
+	}
+
+	horizonMock.AssertExpectations(t)
 }
 
 func TestValidateTransactionOperationsForSuccess(t *testing.T) {
@@ -1420,8 +2474,22 @@ func TestConvertAmountToReadableString(t *testing.T) {
 	parsedAmount, err := amount.ParseInt64("500")
 	require.NoError(t, err)
 	assert.Equal(t, int64(5000000000), parsedAmount)
-
-	readableAmount, err := convertAmountToReadableString(parsedAmount)
-	require.NoError(t, err)
-	assert.Equal(t, "500.00", readableAmount)
+	assert.Equal(t, "500.00", convertAmountToReadableString(parsedAmount))
+
+	testCases := []struct {
+		amount int64
+		want   string
+	}{
+		{amount: 1, want: "0.00"},
+		{amount: 123456789000, want: "12345.68"},
+		{amount: 1234567890000, want: "123456.79"},
+		// half cents are rounded up, which a float64 of 1.005 would not
+		{amount: 10050000, want: "1.01"},
+		{amount: 5140050000, want: "514.01"},
+		{amount: 4237128730050000, want: "423712873.01"},
+		{amount: math.MaxInt64, want: "922337203685.48"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, convertAmountToReadableString(tc.amount), "amount %d", tc.amount)
+	}
 }
@@ -50,6 +50,14 @@ var (
 	// ErrStatementTimeout is an error returned by Session methods when request has
 	// been cancelled due to a statement timeout.
 	ErrStatementTimeout = errors.New("canceling statement due to statement timeout")
+	// ErrSerializationFailure is an error returned by Session methods when
+	// the transaction could not be serialized with concurrent transactions
+	// (SQLSTATE 40001). Retrying the transaction may succeed.
+	ErrSerializationFailure = errors.New("could not serialize access due to concurrent update")
+	// ErrDeadlock is an error returned by Session methods when the query was
+	// aborted to resolve a deadlock (SQLSTATE 40P01). Retrying the transaction
+	// may succeed.
+	ErrDeadlock = errors.New("deadlock detected")
 )
 
 // Conn represents a connection to a single database.
@@ -115,8 +123,24 @@ type Session struct {
 	// DB is the database connection that queries should be executed against.
 	DB *sqlx.DB
 
+	// RouteStatementTimeouts overrides the statement timeout of queries run
+	// on behalf of a route (see RouteContextKey), so expensive endpoints can get
+	// more time without loosening the default. Get, Select and Exec run outside
+	// of a transaction are run in a transaction of their own to set it. Query
+	// is only covered within a transaction, as the rows it returns outlive the
+	// call.
+	RouteStatementTimeouts map[string]time.Duration
+
+	// SlowQueryThreshold, when greater than zero, logs at WARN level every
+	// query taking longer than it to complete. Bound arguments are never
+	// logged.
+	SlowQueryThreshold time.Duration
+
 	tx        *sqlx.Tx
 	txOptions *sql.TxOptions
+	// txRouteTimeoutSet is true when a route statement timeout was already
+	// set in the current transaction.
+	txRouteTimeoutSet bool
 }
 
 type SessionInterface interface {
@@ -146,6 +170,13 @@ type SessionInterface interface {
 		table string,
 		idCol string,
 	) error
+	DeleteRangeBatched(
+		ctx context.Context,
+		start, end int64,
+		table string,
+		idCol string,
+		batchSize int64,
+	) error
 }
 
 // Table helps to build sql queries against a given table.  It logically
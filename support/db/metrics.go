@@ -5,10 +5,13 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/log"
 )
 
 type CtxKey string
@@ -46,6 +49,9 @@ type SessionWithMetrics struct {
 	registry                 *prometheus.Registry
 	queryCounter             *prometheus.CounterVec
 	queryDurationSummary     *prometheus.SummaryVec
+	retryCounter             *prometheus.CounterVec
+	txnCounter               *prometheus.CounterVec
+	txnDurationSummary       *prometheus.SummaryVec
 	maxOpenConnectionsGauge  prometheus.GaugeFunc
 	openConnectionsGauge     prometheus.GaugeFunc
 	inUseConnectionsGauge    prometheus.GaugeFunc
@@ -57,12 +63,40 @@ type SessionWithMetrics struct {
 	maxLifetimeClosedCounter prometheus.CounterFunc
 	roundTripProbe           *roundTripProbe
 	roundTripTimeSummary     prometheus.Summary
+
+	// undefinedQueryLogSampling and undefinedQueryCount sample the queries
+	// logged by queryType, undefinedQueryCount is shared by clones.
+	undefinedQueryLogSampling uint64
+	undefinedQueryCount       *uint64
+
+	// txnStart is the time the current transaction began, zero when the
+	// session is not within a transaction.
+	txnStart time.Time
+}
+
+// MetricsOptions configures the metrics reported by a session returned by
+// RegisterMetrics. The zero value reports all metrics.
+type MetricsOptions struct {
+	// DisableRoundTripProbe stops the session from running `select 1` in the
+	// background to measure the round trip time to the DB. It's useful for
+	// short-lived sessions.
+	DisableRoundTripProbe bool
+	// RoundTripProbeInterval is the time between two round trip probes.
+	// Defaults to 1 second when not positive.
+	RoundTripProbeInterval time.Duration
+	// UndefinedQueryLogSampling, when greater than zero, logs one out of
+	// UndefinedQueryLogSampling queries whose type can't be detected and are
+	// reported as "undefined", to help extending the detection. It's meant
+	// for debugging and disabled by default.
+	UndefinedQueryLogSampling uint64
 }
 
-func RegisterMetrics(base *Session, namespace string, sub Subservice, registry *prometheus.Registry) SessionInterface {
+func RegisterMetrics(base *Session, namespace string, sub Subservice, registry *prometheus.Registry, opts MetricsOptions) SessionInterface {
 	s := &SessionWithMetrics{
-		SessionInterface: base,
-		registry:         registry,
+		SessionInterface:          base,
+		registry:                  registry,
+		undefinedQueryLogSampling: opts.UndefinedQueryLogSampling,
+		undefinedQueryCount:       new(uint64),
 	}
 
 	s.queryCounter = prometheus.NewCounterVec(
@@ -86,18 +120,41 @@ func RegisterMetrics(base *Session, namespace string, sub Subservice, registry *
 	)
 	registry.MustRegister(s.queryDurationSummary)
 
-	// txnCounter: prometheus.NewCounter(
-	// 	prometheus.CounterOpts{Namespace: namespace, Subsystem: "db", Name: "transaction_total"},
-	// ),
-	// registry.MustRegister(s.txnCounter)
-	// txnDuration: prometheus.NewHistogram(
-	// 	prometheus.HistogramOpts{
-	// 		Namespace: namespace, Subsystem: "db",
-	// 		Name:    "transaction_duration_seconds",
-	//		Buckets: prometheus.ExponentialBuckets(0.1, 3, 5),
-	// 	},
-	// ),
-	// registry.MustRegister(s.txnDuration)
+	s.retryCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   "db",
+			Name:        "query_retry_total",
+			Help:        "total number of queries retried after a serialization failure or deadlock, see WithRetry",
+			ConstLabels: prometheus.Labels{"subservice": string(sub)},
+		},
+		[]string{"query_type", "route"},
+	)
+	registry.MustRegister(s.retryCounter)
+
+	s.txnCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   "db",
+			Name:        "transaction_total",
+			Help:        "total number of transactions finished, labeled by whether they were committed or rolled back",
+			ConstLabels: prometheus.Labels{"subservice": string(sub)},
+		},
+		[]string{"outcome", "error"},
+	)
+	registry.MustRegister(s.txnCounter)
+
+	s.txnDurationSummary = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Namespace:   namespace,
+			Subsystem:   "db",
+			Name:        "transaction_duration_seconds",
+			Help:        "time elapsed between beginning a transaction and committing or rolling it back",
+			ConstLabels: prometheus.Labels{"subservice": string(sub)},
+		},
+		[]string{"outcome", "error"},
+	)
+	registry.MustRegister(s.txnDurationSummary)
 
 	s.maxOpenConnectionsGauge = prometheus.NewGaugeFunc(
 		prometheus.GaugeOpts{
@@ -235,21 +292,31 @@ func RegisterMetrics(base *Session, namespace string, sub Subservice, registry *
 	)
 	registry.MustRegister(s.roundTripTimeSummary)
 
-	s.roundTripProbe = &roundTripProbe{
-		session:              base,
-		roundTripTimeSummary: s.roundTripTimeSummary,
+	if !opts.DisableRoundTripProbe {
+		interval := opts.RoundTripProbeInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		s.roundTripProbe = &roundTripProbe{
+			session:              base,
+			roundTripTimeSummary: s.roundTripTimeSummary,
+			interval:             interval,
+		}
+		s.roundTripProbe.start()
 	}
-	s.roundTripProbe.start()
 	return s
 }
 
 func (s *SessionWithMetrics) Close() error {
-	s.roundTripProbe.close()
+	if s.roundTripProbe != nil {
+		s.roundTripProbe.close()
+	}
 
 	s.registry.Unregister(s.queryCounter)
 	s.registry.Unregister(s.queryDurationSummary)
-	// s.registry.Unregister(s.txnCounter)
-	// s.registry.Unregister(s.txnDurationSummary)
+	s.registry.Unregister(s.retryCounter)
+	s.registry.Unregister(s.txnCounter)
+	s.registry.Unregister(s.txnDurationSummary)
 	s.registry.Unregister(s.maxOpenConnectionsGauge)
 	s.registry.Unregister(s.openConnectionsGauge)
 	s.registry.Unregister(s.inUseConnectionsGauge)
@@ -262,11 +329,68 @@ func (s *SessionWithMetrics) Close() error {
 	return s.SessionInterface.Close()
 }
 
-// TODO: Implement these
-// func (s *SessionWithMetrics) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
-// func (s *SessionWithMetrics) Begin(ctx context.Context) error {
-// func (s *SessionWithMetrics) Commit(ctx context.Context) error
-// func (s *SessionWithMetrics) Rollback(ctx context.Context) error
+func (s *SessionWithMetrics) BeginTx(opts *sql.TxOptions) error {
+	err := s.SessionInterface.BeginTx(opts)
+	if err == nil {
+		s.txnStart = time.Now()
+	}
+	return err
+}
+
+func (s *SessionWithMetrics) Begin() error {
+	err := s.SessionInterface.Begin()
+	if err == nil {
+		s.txnStart = time.Now()
+	}
+	return err
+}
+
+func (s *SessionWithMetrics) Commit() error {
+	err := s.SessionInterface.Commit()
+	s.observeTxn("commit", err)
+	return err
+}
+
+func (s *SessionWithMetrics) Rollback() error {
+	err := s.SessionInterface.Rollback()
+	s.observeTxn("rollback", err)
+	return err
+}
+
+// observeTxn records the outcome and duration of the current transaction, if
+// the session is within one.
+func (s *SessionWithMetrics) observeTxn(outcome string, err error) {
+	if s.txnStart.IsZero() {
+		return
+	}
+	labels := prometheus.Labels{
+		"outcome": outcome,
+		"error":   fmt.Sprint(err != nil),
+	}
+	s.txnDurationSummary.With(labels).Observe(time.Since(s.txnStart).Seconds())
+	s.txnCounter.With(labels).Inc()
+	s.txnStart = time.Time{}
+}
+
+// WithRetry returns a session retrying Exec, Get and Select up to maxAttempts
+// times, with a jittered exponential backoff, when they fail with
+// ErrSerializationFailure or ErrDeadlock outside of a transaction. Every retry
+// is counted in the query_retry_total metric. maxAttempts must be at least 1.
+func (s *SessionWithMetrics) WithRetry(maxAttempts int) (SessionInterface, error) {
+	if maxAttempts < 1 {
+		return nil, errors.Errorf("maxAttempts must be at least 1, got %d", maxAttempts)
+	}
+	return &retrySession{
+		SessionInterface: s,
+		maxAttempts:      maxAttempts,
+		onRetry: func(ctx context.Context, queryType QueryType) {
+			s.retryCounter.With(prometheus.Labels{
+				"query_type": string(queryType),
+				"route":      contextRoute(ctx),
+			}).Inc()
+		},
+	}, nil
+}
 
 func (s *SessionWithMetrics) TruncateTables(ctx context.Context, tables []string) (err error) {
 	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
@@ -297,11 +421,12 @@ func (s *SessionWithMetrics) Clone() SessionInterface {
 		// to avoid starting multiple go routines.
 		roundTripProbe: s.roundTripProbe,
 
-		registry:             s.registry,
-		queryCounter:         s.queryCounter,
-		queryDurationSummary: s.queryDurationSummary,
-		// txnCounter:               s.txnCounter,
-		// txnDurationSummary:       s.txnDurationSummary,
+		registry:                 s.registry,
+		queryCounter:             s.queryCounter,
+		queryDurationSummary:     s.queryDurationSummary,
+		retryCounter:             s.retryCounter,
+		txnCounter:               s.txnCounter,
+		txnDurationSummary:       s.txnDurationSummary,
 		maxOpenConnectionsGauge:  s.maxOpenConnectionsGauge,
 		openConnectionsGauge:     s.openConnectionsGauge,
 		inUseConnectionsGauge:    s.inUseConnectionsGauge,
@@ -311,6 +436,9 @@ func (s *SessionWithMetrics) Clone() SessionInterface {
 		maxIdleClosedCounter:     s.maxIdleClosedCounter,
 		maxIdleTimeClosedCounter: s.maxIdleTimeClosedCounter,
 		maxLifetimeClosedCounter: s.maxLifetimeClosedCounter,
+
+		undefinedQueryLogSampling: s.undefinedQueryLogSampling,
+		undefinedQueryCount:       s.undefinedQueryCount,
 	}
 }
 
@@ -353,8 +481,30 @@ func getQueryType(ctx context.Context, query squirrel.Sqlizer) QueryType {
 	return UndefinedQueryType
 }
 
+// maxUndefinedQueryLogLength is the number of characters of an undefined
+// query's SQL included in the log line.
+const maxUndefinedQueryLogLength = 120
+
+// queryType returns the type of query like getQueryType, logging a sample of
+// the undefined queries when enabled in MetricsOptions.
+func (s *SessionWithMetrics) queryType(ctx context.Context, query squirrel.Sqlizer) QueryType {
+	queryType := getQueryType(ctx, query)
+	if queryType != UndefinedQueryType || s.undefinedQueryLogSampling == 0 {
+		return queryType
+	}
+
+	if (atomic.AddUint64(s.undefinedQueryCount, 1)-1)%s.undefinedQueryLogSampling == 0 {
+		str, _, _ := query.ToSql()
+		if len(str) > maxUndefinedQueryLogLength {
+			str = str[:maxUndefinedQueryLogLength]
+		}
+		log.Ctx(ctx).WithField("sql", str).Info("sql: undefined query type")
+	}
+	return queryType
+}
+
 func (s *SessionWithMetrics) Get(ctx context.Context, dest interface{}, query squirrel.Sqlizer) (err error) {
-	queryType := string(getQueryType(ctx, query))
+	queryType := string(s.queryType(ctx, query))
 	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
 		s.queryDurationSummary.With(prometheus.Labels{
 			"query_type": queryType,
@@ -380,7 +530,7 @@ func (s *SessionWithMetrics) GetRaw(ctx context.Context, dest interface{}, query
 }
 
 func (s *SessionWithMetrics) Select(ctx context.Context, dest interface{}, query squirrel.Sqlizer) (err error) {
-	queryType := string(getQueryType(ctx, query))
+	queryType := string(s.queryType(ctx, query))
 	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
 		s.queryDurationSummary.With(prometheus.Labels{
 			"query_type": queryType,
@@ -406,7 +556,7 @@ func (s *SessionWithMetrics) SelectRaw(ctx context.Context, dest interface{}, qu
 }
 
 func (s *SessionWithMetrics) Exec(ctx context.Context, query squirrel.Sqlizer) (result sql.Result, err error) {
-	queryType := string(getQueryType(ctx, query))
+	queryType := string(s.queryType(ctx, query))
 	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
 		s.queryDurationSummary.With(prometheus.Labels{
 			"query_type": queryType,
@@ -479,3 +629,31 @@ func (s *SessionWithMetrics) DeleteRange(
 	err = s.SessionInterface.DeleteRange(ctx, start, end, table, idCol)
 	return err
 }
+
+func (s *SessionWithMetrics) DeleteRangeBatched(
+	ctx context.Context,
+	start, end int64,
+	table string,
+	idCol string,
+	batchSize int64,
+) (err error) {
+	queryType := "delete"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		s.queryDurationSummary.With(prometheus.Labels{
+			"query_type": queryType,
+			"error":      fmt.Sprint(err != nil),
+			"route":      contextRoute(ctx),
+		}).Observe(v)
+	}))
+	defer func() {
+		timer.ObserveDuration()
+		s.queryCounter.With(prometheus.Labels{
+			"query_type": queryType,
+			"error":      fmt.Sprint(err != nil),
+			"route":      contextRoute(ctx),
+		}).Inc()
+	}()
+
+	err = s.SessionInterface.DeleteRangeBatched(ctx, start, end, table, idCol, batchSize)
+	return err
+}
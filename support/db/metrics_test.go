@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stellar/go/support/db/dbtest"
+	"github.com/stellar/go/support/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func summarySampleCount(t *testing.T, summary *prometheus.SummaryVec, labels prometheus.Labels) uint64 {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(summary))
+	defer registry.Unregister(summary)
+
+	metricFamilies, err := registry.Gather()
+	require.NoError(t, err)
+	for _, mf := range metricFamilies {
+		for _, m := range mf.GetMetric() {
+			matches := true
+			for _, label := range m.GetLabel() {
+				if value, ok := labels[label.GetName()]; ok && value != label.GetValue() {
+					matches = false
+				}
+			}
+			if matches {
+				return m.GetSummary().GetSampleCount()
+			}
+		}
+	}
+	return 0
+}
+
+func TestSessionWithMetricsTransactions(t *testing.T) {
+	db := dbtest.Postgres(t).Load(testSchema)
+	defer db.Close()
+
+	sess := RegisterMetrics(&Session{DB: db.Open()}, "test", CoreSubservice, prometheus.NewRegistry(), MetricsOptions{})
+	defer sess.Close()
+	metrics := sess.(*SessionWithMetrics)
+
+	commitLabels := prometheus.Labels{"outcome": "commit", "error": "false"}
+	rollbackLabels := prometheus.Labels{"outcome": "rollback", "error": "false"}
+
+	require.NoError(t, sess.Begin())
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, sess.Commit())
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.txnCounter.With(commitLabels)))
+	assert.Equal(t, uint64(1), summarySampleCount(t, metrics.txnDurationSummary, commitLabels))
+
+	require.NoError(t, sess.Begin())
+	require.NoError(t, sess.Rollback())
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.txnCounter.With(rollbackLabels)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.txnCounter.With(commitLabels)))
+
+	// finishing outside of a transaction isn't recorded
+	assert.Error(t, sess.Rollback())
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.txnCounter.With(rollbackLabels)))
+}
+
+func TestRegisterMetricsWithoutRoundTripProbe(t *testing.T) {
+	sess := RegisterMetrics(&Session{}, "test", CoreSubservice, prometheus.NewRegistry(), MetricsOptions{
+		DisableRoundTripProbe:  true,
+		RoundTripProbeInterval: time.Millisecond,
+	})
+	metrics := sess.(*SessionWithMetrics)
+	assert.Nil(t, metrics.roundTripProbe)
+
+	time.Sleep(10 * time.Millisecond)
+	metric := &dto.Metric{}
+	require.NoError(t, metrics.roundTripTimeSummary.Write(metric))
+	assert.Equal(t, uint64(0), metric.GetSummary().GetSampleCount())
+}
+
+func TestRegisterMetricsNegativeRoundTripProbeInterval(t *testing.T) {
+	sess := RegisterMetrics(&Session{}, "test", CoreSubservice, prometheus.NewRegistry(), MetricsOptions{
+		RoundTripProbeInterval: -time.Second,
+	})
+	metrics := sess.(*SessionWithMetrics)
+	require.NotNil(t, metrics.roundTripProbe)
+	defer metrics.roundTripProbe.close()
+	assert.Equal(t, time.Second, metrics.roundTripProbe.interval)
+}
+
+func TestSessionWithMetricsUndefinedQueryLogging(t *testing.T) {
+	ctx := context.Background()
+	cte := squirrel.Expr("WITH r AS (SELECT unnest(?::text[]) AS name) INSERT INTO people (name, hunger_level) SELECT name, 0 FROM r", "{jed}")
+
+	// disabled by default
+	sess := &SessionWithMetrics{undefinedQueryCount: new(uint64)}
+	done := log.DefaultLogger.StartTest(log.InfoLevel)
+	assert.Equal(t, UndefinedQueryType, sess.queryType(ctx, cte))
+	assert.Empty(t, done())
+
+	// one out of two undefined queries is logged
+	sess = &SessionWithMetrics{undefinedQueryLogSampling: 2, undefinedQueryCount: new(uint64)}
+	done = log.DefaultLogger.StartTest(log.InfoLevel)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, UndefinedQueryType, sess.queryType(ctx, cte))
+	}
+	assert.Equal(t, SelectQueryType, sess.queryType(ctx, squirrel.Expr("SELECT 1")))
+	logged := done()
+	require.Len(t, logged, 2)
+	assert.Equal(t, "sql: undefined query type", logged[0].Message)
+	assert.Equal(t, "WITH r AS (SELECT unnest(?::text[]) AS name) INSERT INTO people (name, hunger_level) SELECT name, 0 FROM r", logged[0].Data["sql"])
+
+	// the query type can be set explicitly in the context
+	insertCtx := context.WithValue(ctx, &QueryTypeContextKey, InsertQueryType)
+	done = log.DefaultLogger.StartTest(log.InfoLevel)
+	assert.Equal(t, InsertQueryType, sess.queryType(insertCtx, cte))
+	assert.Empty(t, done())
+}
@@ -128,3 +128,13 @@ func (m *MockSession) DeleteRange(
 ) (err error) {
 	return m.Called(ctx, start, end, table, idCol).Error(0)
 }
+
+func (m *MockSession) DeleteRangeBatched(
+	ctx context.Context,
+	start, end int64,
+	table string,
+	idCol string,
+	batchSize int64,
+) (err error) {
+	return m.Called(ctx, start, end, table, idCol, batchSize).Error(0)
+}
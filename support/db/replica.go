@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+)
+
+var ReplicaContextKey = CtxKey("replica")
+
+// WithReplica returns a copy of ctx hinting that reads run with it can be
+// served by a read-only replica.
+func WithReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, &ReplicaContextKey, true)
+}
+
+// replicaRequested returns true if ctx was tagged by WithReplica.
+func replicaRequested(ctx context.Context) bool {
+	replica, ok := ctx.Value(&ReplicaContextKey).(bool)
+	return ok && replica
+}
+
+// PoolSelector picks the session a read query should run against.
+type PoolSelector interface {
+	ReadPool(ctx context.Context) SessionInterface
+}
+
+var _ SessionInterface = (*ReplicaSession)(nil)
+var _ PoolSelector = (*ReplicaSession)(nil)
+
+// ReplicaSession is a SessionInterface running writes, and any query within a
+// transaction, against the primary session. Get and Select run with a context
+// tagged by WithReplica are served by the replica session instead. Query is
+// always run against the primary session as it is used for statements such as
+// INSERT ... RETURNING.
+type ReplicaSession struct {
+	SessionInterface
+
+	Replica SessionInterface
+}
+
+// ReadPool returns the replica session if ctx was tagged by WithReplica and the
+// session is not within a transaction, the primary session otherwise.
+func (s *ReplicaSession) ReadPool(ctx context.Context) SessionInterface {
+	if replicaRequested(ctx) && s.SessionInterface.GetTx() == nil {
+		return s.Replica
+	}
+	return s.SessionInterface
+}
+
+func (s *ReplicaSession) Clone() SessionInterface {
+	return &ReplicaSession{
+		SessionInterface: s.SessionInterface.Clone(),
+		Replica:          s.Replica.Clone(),
+	}
+}
+
+func (s *ReplicaSession) Close() error {
+	if err := s.Replica.Close(); err != nil {
+		return err
+	}
+	return s.SessionInterface.Close()
+}
+
+func (s *ReplicaSession) Get(ctx context.Context, dest interface{}, query squirrel.Sqlizer) error {
+	return s.ReadPool(ctx).Get(ctx, dest, query)
+}
+
+func (s *ReplicaSession) GetRaw(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return s.ReadPool(ctx).GetRaw(ctx, dest, query, args...)
+}
+
+func (s *ReplicaSession) Select(ctx context.Context, dest interface{}, query squirrel.Sqlizer) error {
+	return s.ReadPool(ctx).Select(ctx, dest, query)
+}
+
+func (s *ReplicaSession) SelectRaw(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return s.ReadPool(ctx).SelectRaw(ctx, dest, query, args...)
+}
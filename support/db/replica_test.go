@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReplicaSession(t *testing.T) {
+	primary := &MockSession{}
+	replica := &MockSession{}
+	sess := &ReplicaSession{SessionInterface: primary, Replica: replica}
+
+	ctx := context.Background()
+	replicaCtx := WithReplica(ctx)
+	selectQuery := squirrel.Select("name").From("people")
+	insertQuery := squirrel.Insert("people").Columns("name").Values("jed")
+	var dest []string
+
+	// untagged reads use the primary
+	primary.On("Select", ctx, &dest, selectQuery).Return(nil).Once()
+	assert.NoError(t, sess.Select(ctx, &dest, selectQuery))
+
+	// tagged reads use the replica
+	primary.On("GetTx").Return((*sqlx.Tx)(nil)).Once()
+	replica.On("Select", replicaCtx, &dest, selectQuery).Return(nil).Once()
+	assert.NoError(t, sess.Select(replicaCtx, &dest, selectQuery))
+
+	// tagged reads within a transaction use the primary
+	primary.On("GetTx").Return(&sqlx.Tx{}).Once()
+	primary.On("GetRaw", replicaCtx, &dest, "SELECT 1", []interface{}(nil)).Return(nil).Once()
+	assert.NoError(t, sess.GetRaw(replicaCtx, &dest, "SELECT 1"))
+
+	// writes always use the primary
+	primary.On("Exec", replicaCtx, insertQuery).Return(driver.RowsAffected(1), nil).Once()
+	_, err := sess.Exec(replicaCtx, insertQuery)
+	assert.NoError(t, err)
+
+	// tagged queries use the primary as they may write
+	primary.On("Query", replicaCtx, insertQuery).Return((*sqlx.Rows)(nil), nil).Once()
+	_, err = sess.Query(replicaCtx, insertQuery)
+	assert.NoError(t, err)
+
+	primary.On("QueryRaw", replicaCtx, "INSERT INTO people (name) VALUES ('jed') RETURNING name", []interface{}(nil)).Return((*sqlx.Rows)(nil), nil).Once()
+	_, err = sess.QueryRaw(replicaCtx, "INSERT INTO people (name) VALUES ('jed') RETURNING name")
+	assert.NoError(t, err)
+
+	primary.AssertExpectations(t)
+	replica.AssertExpectations(t)
+	replica.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything)
+	replica.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+	replica.AssertNotCalled(t, "QueryRaw", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/stellar/go/support/errors"
+)
+
+// retryBaseBackoff is the backoff before the first retry, doubled for every
+// following attempt up to retryMaxBackoff.
+var retryBaseBackoff = 10 * time.Millisecond
+
+// retryMaxBackoff caps the backoff before jitter is added.
+var retryMaxBackoff = time.Second
+
+// retryableError returns true if err is a concurrency error which may not
+// happen again if the query is retried.
+func retryableError(err error) bool {
+	cause := errors.Cause(err)
+	return cause == ErrSerializationFailure || cause == ErrDeadlock
+}
+
+// retrySession is a SessionInterface retrying Exec, Get and Select up to
+// maxAttempts times when they fail with ErrSerializationFailure or
+// ErrDeadlock. Queries run within a transaction are never retried because
+// postgres aborts the whole transaction on such errors.
+type retrySession struct {
+	SessionInterface
+
+	maxAttempts int
+	onRetry     func(ctx context.Context, queryType QueryType)
+}
+
+func (s *retrySession) Clone() SessionInterface {
+	return &retrySession{
+		SessionInterface: s.SessionInterface.Clone(),
+		maxAttempts:      s.maxAttempts,
+		onRetry:          s.onRetry,
+	}
+}
+
+func (s *retrySession) retry(ctx context.Context, query squirrel.Sqlizer, fn func() error) error {
+	err := fn()
+	for attempt := 1; attempt < s.maxAttempts && retryableError(err) && s.GetTx() == nil; attempt++ {
+		backoff := retryBackoff(attempt)
+		if backoff > 0 {
+			backoff += time.Duration(rand.Int63n(int64(backoff)))
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		if s.onRetry != nil {
+			s.onRetry(ctx, getQueryType(ctx, query))
+		}
+		err = fn()
+	}
+	return err
+}
+
+// retryBackoff returns the backoff before the given retry attempt, without
+// jitter, doubling retryBaseBackoff for every attempt up to retryMaxBackoff.
+func retryBackoff(attempt int) time.Duration {
+	backoff := retryBaseBackoff
+	for i := 1; i < attempt && backoff < retryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > retryMaxBackoff {
+		backoff = retryMaxBackoff
+	}
+	return backoff
+}
+
+func (s *retrySession) Exec(ctx context.Context, query squirrel.Sqlizer) (result sql.Result, err error) {
+	err = s.retry(ctx, query, func() error {
+		result, err = s.SessionInterface.Exec(ctx, query)
+		return err
+	})
+	return result, err
+}
+
+func (s *retrySession) ExecRaw(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.Exec(ctx, squirrel.Expr(query, args...))
+}
+
+func (s *retrySession) Get(ctx context.Context, dest interface{}, query squirrel.Sqlizer) error {
+	return s.retry(ctx, query, func() error {
+		return s.SessionInterface.Get(ctx, dest, query)
+	})
+}
+
+func (s *retrySession) GetRaw(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return s.Get(ctx, dest, squirrel.Expr(query, args...))
+}
+
+func (s *retrySession) Select(ctx context.Context, dest interface{}, query squirrel.Sqlizer) error {
+	return s.retry(ctx, query, func() error {
+		return s.SessionInterface.Select(ctx, dest, query)
+	})
+}
+
+func (s *retrySession) SelectRaw(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return s.Select(ctx, dest, squirrel.Expr(query, args...))
+}
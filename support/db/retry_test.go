@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stellar/go/support/db/dbtest"
+	"github.com/stellar/go/support/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetrySession(t *testing.T) {
+	defer func(backoff time.Duration) { retryBaseBackoff = backoff }(retryBaseBackoff)
+	retryBaseBackoff = time.Millisecond
+
+	ctx := context.Background()
+	query := squirrel.Update("people").Set("hunger_level", 11).Where("name = ?", "jed")
+	retries := map[QueryType]int{}
+	newSession := func(mockSession *MockSession) *retrySession {
+		return &retrySession{
+			SessionInterface: mockSession,
+			maxAttempts:      3,
+			onRetry: func(ctx context.Context, queryType QueryType) {
+				retries[queryType]++
+			},
+		}
+	}
+
+	// retried until it succeeds
+	mockSession := &MockSession{}
+	mockSession.On("GetTx").Return((*sqlx.Tx)(nil))
+	mockSession.On("Exec", ctx, query).Return(driver.RowsAffected(0), errors.Wrap(ErrSerializationFailure, "exec failed")).Once()
+	mockSession.On("Exec", ctx, query).Return(driver.RowsAffected(0), ErrDeadlock).Once()
+	mockSession.On("Exec", ctx, query).Return(driver.RowsAffected(0), nil).Once()
+	_, err := newSession(mockSession).Exec(ctx, query)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, retries[UpdateQueryType])
+	mockSession.AssertExpectations(t)
+
+	// gives up after maxAttempts
+	retries = map[QueryType]int{}
+	mockSession = &MockSession{}
+	mockSession.On("GetTx").Return((*sqlx.Tx)(nil))
+	mockSession.On("Exec", ctx, query).Return(driver.RowsAffected(0), ErrSerializationFailure).Times(3)
+	_, err = newSession(mockSession).Exec(ctx, query)
+	assert.Equal(t, ErrSerializationFailure, err)
+	assert.Equal(t, 2, retries[UpdateQueryType])
+	mockSession.AssertExpectations(t)
+
+	// other errors aren't retried
+	retries = map[QueryType]int{}
+	mockSession = &MockSession{}
+	mockSession.On("Exec", ctx, query).Return(driver.RowsAffected(0), ErrStatementTimeout).Once()
+	_, err = newSession(mockSession).Exec(ctx, query)
+	assert.Equal(t, ErrStatementTimeout, err)
+	assert.Empty(t, retries)
+	mockSession.AssertExpectations(t)
+
+	// queries within a transaction aren't retried
+	mockSession = &MockSession{}
+	mockSession.On("GetTx").Return(&sqlx.Tx{})
+	mockSession.On("Exec", ctx, query).Return(driver.RowsAffected(0), ErrSerializationFailure).Once()
+	_, err = newSession(mockSession).Exec(ctx, query)
+	assert.Equal(t, ErrSerializationFailure, err)
+	assert.Empty(t, retries)
+	mockSession.AssertExpectations(t)
+}
+
+func TestRetrySessionBackoffBounds(t *testing.T) {
+	defer func(backoff time.Duration) { retryBaseBackoff = backoff }(retryBaseBackoff)
+
+	retryBaseBackoff = 10 * time.Millisecond
+	assert.Equal(t, 10*time.Millisecond, retryBackoff(1))
+	assert.Equal(t, 20*time.Millisecond, retryBackoff(2))
+	for _, attempt := range []int{8, 41, 64, 1000} {
+		assert.Equal(t, retryMaxBackoff, retryBackoff(attempt))
+	}
+
+	// a zero base backoff with many attempts neither panics nor overflows
+	retryBaseBackoff = 0
+	ctx := context.Background()
+	query := squirrel.Update("people").Set("hunger_level", 11).Where("name = ?", "jed")
+	mockSession := &MockSession{}
+	mockSession.On("GetTx").Return((*sqlx.Tx)(nil))
+	mockSession.On("Exec", ctx, query).Return(driver.RowsAffected(0), ErrSerializationFailure).Times(100)
+	session := &retrySession{SessionInterface: mockSession, maxAttempts: 100}
+	_, err := session.Exec(ctx, query)
+	assert.Equal(t, ErrSerializationFailure, err)
+	mockSession.AssertExpectations(t)
+}
+
+func TestWithRetryInvalidMaxAttempts(t *testing.T) {
+	metrics := &SessionWithMetrics{SessionInterface: &MockSession{}}
+	_, err := metrics.WithRetry(0)
+	assert.EqualError(t, err, "maxAttempts must be at least 1, got 0")
+}
+
+func TestRetrySessionSerializationFailure(t *testing.T) {
+	db := dbtest.Postgres(t).Load(testSchema)
+	defer db.Close()
+
+	holder := &Session{DB: db.Open()}
+	defer holder.DB.Close()
+
+	// a single connection so the isolation level applies to every query
+	retrierDB := db.Open()
+	retrierDB.SetMaxOpenConns(1)
+	metrics := RegisterMetrics(&Session{DB: retrierDB}, "test", CoreSubservice, prometheus.NewRegistry(), MetricsOptions{
+		DisableRoundTripProbe: true,
+	}).(*SessionWithMetrics)
+	defer metrics.Close()
+	ctx := context.Background()
+	_, err := metrics.ExecRaw(ctx, "SET SESSION CHARACTERISTICS AS TRANSACTION ISOLATION LEVEL REPEATABLE READ")
+	require.NoError(t, err)
+	retrier, err := metrics.WithRetry(3)
+	require.NoError(t, err)
+
+	locked := make(chan struct{})
+	holderErr := make(chan error, 1)
+	retrierErr := make(chan error, 1)
+
+	// holds the row lock until the retrier waits for it, then commits a
+	// concurrent update making the retrier fail with 40001
+	go func() {
+		holderErr <- func() error {
+			if err := holder.Begin(); err != nil {
+				close(locked)
+				return err
+			}
+			defer holder.Rollback()
+			if _, err := holder.ExecRaw(ctx, "UPDATE people SET hunger_level = 11 WHERE name = 'jed'"); err != nil {
+				close(locked)
+				return err
+			}
+			close(locked)
+
+			observer := &Session{DB: holder.DB}
+			deadline := time.Now().Add(10 * time.Second)
+			for waiting := 0; waiting == 0; {
+				if time.Now().After(deadline) {
+					return errors.New("retrier never waited for the row lock")
+				}
+				time.Sleep(10 * time.Millisecond)
+				err := observer.GetRaw(ctx, &waiting, "SELECT COUNT(*) FROM pg_stat_activity WHERE wait_event_type = 'Lock' AND query LIKE 'UPDATE people%'")
+				if err != nil {
+					return err
+				}
+			}
+			return holder.Commit()
+		}()
+	}()
+
+	go func() {
+		<-locked
+		_, err := retrier.ExecRaw(ctx, "UPDATE people SET hunger_level = 12 WHERE name = 'jed'")
+		retrierErr <- err
+	}()
+
+	require.NoError(t, <-holderErr)
+	require.NoError(t, <-retrierErr)
+
+	var hungerLevel int
+	require.NoError(t, holder.GetRaw(ctx, &hungerLevel, "SELECT hunger_level FROM people WHERE name = 'jed'"))
+	assert.Equal(t, 12, hungerLevel)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.retryCounter.With(prometheus.Labels{
+		"query_type": string(UpdateQueryType),
+		"route":      contextRoute(ctx),
+	})))
+}
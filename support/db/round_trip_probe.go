@@ -11,6 +11,7 @@ import (
 type roundTripProbe struct {
 	session              SessionInterface
 	roundTripTimeSummary prometheus.Summary
+	interval             time.Duration
 
 	closeChan chan struct{}
 	closeOnce sync.Once
@@ -21,7 +22,7 @@ func (p *roundTripProbe) start() {
 	// session must be cloned because will be used concurrently in a
 	// separate go routine in roundTripProbe
 	p.session = p.session.Clone()
-	ticker := time.NewTicker(time.Second)
+	ticker := time.NewTicker(p.interval)
 
 	go func() {
 		for {
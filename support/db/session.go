@@ -10,6 +10,7 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stellar/go/support/db/sqlutils"
 	"github.com/stellar/go/support/errors"
 	"github.com/stellar/go/support/log"
@@ -32,6 +33,7 @@ func (s *Session) Begin() error {
 	log.Debug("sql: begin")
 	s.tx = tx
 	s.txOptions = nil
+	s.txRouteTimeoutSet = false
 	return nil
 }
 
@@ -54,6 +56,7 @@ func (s *Session) BeginTx(opts *sql.TxOptions) error {
 
 	s.tx = tx
 	s.txOptions = opts
+	s.txRouteTimeoutSet = false
 	return nil
 }
 
@@ -70,7 +73,9 @@ func (s *Session) GetTxOptions() *sql.TxOptions {
 // source is currently within.
 func (s *Session) Clone() SessionInterface {
 	return &Session{
-		DB: s.DB,
+		DB:                     s.DB,
+		RouteStatementTimeouts: s.RouteStatementTimeouts,
+		SlowQueryThreshold:     s.SlowQueryThreshold,
 	}
 }
 
@@ -91,6 +96,7 @@ func (s *Session) Commit() error {
 	log.Debug("sql: commit")
 	s.tx = nil
 	s.txOptions = nil
+	s.txRouteTimeoutSet = false
 
 	if knownErr := s.replaceWithKnownError(err, context.Background()); knownErr != nil {
 		return knownErr
@@ -120,6 +126,55 @@ func (s *Session) DeleteRange(
 	return err
 }
 
+// DeleteRangeBatched deletes a range of rows from a sql table between `start`
+// and `end` (exclusive) like DeleteRange, but does it in chunks of at most
+// `batchSize` ids in `idCol` order. Outside of a transaction every chunk is
+// committed on its own, so locks are held briefly even for huge ranges. The
+// context is checked between chunks: when it's cancelled, rows deleted by the
+// previous chunks stay deleted.
+func (s *Session) DeleteRangeBatched(
+	ctx context.Context,
+	start, end int64,
+	table string,
+	idCol string,
+	batchSize int64,
+) error {
+	if batchSize <= 0 {
+		return errors.New("batch size must be greater than 0")
+	}
+
+	ids := sq.Select(idCol).
+		From(table).
+		Where(fmt.Sprintf("%s >= ? AND %s < ?", idCol, idCol), start, end).
+		OrderBy(idCol).
+		Limit(uint64(batchSize))
+	idsSQL, idsArgs, err := ids.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "building batch select")
+	}
+	del := sq.Delete(table).Where(fmt.Sprintf("%s IN (%s)", idCol, idsSQL), idsArgs...)
+
+	var result sql.Result
+	var deleted int64
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		result, err = s.Exec(ctx, del)
+		if err != nil {
+			return err
+		}
+		deleted, err = result.RowsAffected()
+		if err != nil {
+			return errors.Wrap(err, "getting rows affected")
+		}
+		if deleted < batchSize {
+			return nil
+		}
+	}
+}
+
 // Get runs `query`, setting the first result found on `dest`, if
 // any.
 func (s *Session) Get(ctx context.Context, dest interface{}, query sq.Sqlizer) error {
@@ -133,11 +188,21 @@ func (s *Session) Get(ctx context.Context, dest interface{}, query sq.Sqlizer) e
 // GetRaw runs `query` with `args`, setting the first result found on
 // `dest`, if any.
 func (s *Session) GetRaw(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if s.needsRouteTimeoutTx(ctx) {
+		return s.inRouteTimeoutTx(func(sess *Session) error {
+			return sess.GetRaw(ctx, dest, query, args...)
+		})
+	}
+
 	query, err := s.ReplacePlaceholders(query)
 	if err != nil {
 		return errors.Wrap(err, "replace placeholders failed")
 	}
 
+	if err = s.setRouteStatementTimeout(ctx); err != nil {
+		return err
+	}
+
 	start := time.Now()
 	err = s.conn().GetContext(ctx, dest, query, args...)
 	s.log(ctx, "get", start, query, args)
@@ -202,11 +267,25 @@ func (s *Session) ExecAll(ctx context.Context, script string) error {
 
 // ExecRaw runs `query` with `args`
 func (s *Session) ExecRaw(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if s.needsRouteTimeoutTx(ctx) {
+		var result sql.Result
+		err := s.inRouteTimeoutTx(func(sess *Session) error {
+			var err error
+			result, err = sess.ExecRaw(ctx, query, args...)
+			return err
+		})
+		return result, err
+	}
+
 	query, err := s.ReplacePlaceholders(query)
 	if err != nil {
 		return nil, errors.Wrap(err, "replace placeholders failed")
 	}
 
+	if err = s.setRouteStatementTimeout(ctx); err != nil {
+		return nil, err
+	}
+
 	start := time.Now()
 	result, err := s.conn().ExecContext(ctx, query, args...)
 	s.log(ctx, "exec", start, query, args)
@@ -253,11 +332,24 @@ func (s *Session) replaceWithKnownError(err error, ctx context.Context) error {
 		return ErrBadConnection
 	case strings.Contains(err.Error(), "pq: canceling statement due to statement timeout"):
 		return ErrStatementTimeout
+	case pqErrorCode(err) == "40001":
+		return ErrSerializationFailure
+	case pqErrorCode(err) == "40P01":
+		return ErrDeadlock
 	default:
 		return nil
 	}
 }
 
+// pqErrorCode returns the SQLSTATE code of err if it was returned by postgres,
+// or an empty string otherwise.
+func pqErrorCode(err error) pq.ErrorCode {
+	if pqErr, ok := errors.Cause(err).(*pq.Error); ok {
+		return pqErr.Code
+	}
+	return ""
+}
+
 // Query runs `query`, returns a *sqlx.Rows instance
 func (s *Session) Query(ctx context.Context, query sq.Sqlizer) (*sqlx.Rows, error) {
 	sql, args, err := s.build(query)
@@ -274,6 +366,10 @@ func (s *Session) QueryRaw(ctx context.Context, query string, args ...interface{
 		return nil, errors.Wrap(err, "replace placeholders failed")
 	}
 
+	if err = s.setRouteStatementTimeout(ctx); err != nil {
+		return nil, err
+	}
+
 	start := time.Now()
 	result, err := s.conn().QueryxContext(ctx, query, args...)
 	s.log(ctx, "query", start, query, args)
@@ -305,6 +401,67 @@ func (s *Session) ReplacePlaceholders(query string) (string, error) {
 	return format.ReplacePlaceholders(query)
 }
 
+// routeStatementTimeout returns the statement timeout configured for the route
+// found in ctx, if any.
+func (s *Session) routeStatementTimeout(ctx context.Context) (time.Duration, bool) {
+	if len(s.RouteStatementTimeouts) == 0 {
+		return 0, false
+	}
+	timeout, ok := s.RouteStatementTimeouts[contextRoute(ctx)]
+	return timeout, ok
+}
+
+// needsRouteTimeoutTx returns true if a query run with ctx outside of a
+// transaction has a route statement timeout, which can only be set within a
+// transaction.
+func (s *Session) needsRouteTimeoutTx(ctx context.Context) bool {
+	if s.tx != nil {
+		return false
+	}
+	_, ok := s.routeStatementTimeout(ctx)
+	return ok
+}
+
+// inRouteTimeoutTx runs fn with a clone of the session bound to a transaction
+// of its own, committed if fn succeeds, so the route statement timeout set by
+// fn applies to a single query without changing the default of the connection.
+func (s *Session) inRouteTimeoutTx(fn func(sess *Session) error) error {
+	sess := s.Clone().(*Session)
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+	if err := fn(sess); err != nil {
+		sess.Rollback()
+		return err
+	}
+	return sess.Commit()
+}
+
+// setRouteStatementTimeout sets the statement timeout configured for the route
+// found in ctx, if any. SET LOCAL is used so the timeout only lasts until the
+// end of the current transaction, which is why it's a no-op outside of one.
+func (s *Session) setRouteStatementTimeout(ctx context.Context) error {
+	if s.tx == nil || s.txRouteTimeoutSet {
+		return nil
+	}
+
+	timeout, ok := s.routeStatementTimeout(ctx)
+	if !ok {
+		return nil
+	}
+
+	_, err := s.tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds()))
+	if err != nil {
+		if knownErr := s.replaceWithKnownError(err, ctx); knownErr != nil {
+			return knownErr
+		}
+
+		return errors.Wrap(err, "setting route statement timeout failed")
+	}
+	s.txRouteTimeoutSet = true
+	return nil
+}
+
 // Rollback rolls back the current transaction
 func (s *Session) Rollback() error {
 	if s.tx == nil {
@@ -315,6 +472,7 @@ func (s *Session) Rollback() error {
 	log.Debug("sql: rollback")
 	s.tx = nil
 	s.txOptions = nil
+	s.txRouteTimeoutSet = false
 
 	if knownErr := s.replaceWithKnownError(err, context.Background()); knownErr != nil {
 		return knownErr
@@ -346,12 +504,22 @@ func (s *Session) SelectRaw(
 	query string,
 	args ...interface{},
 ) error {
+	if s.needsRouteTimeoutTx(ctx) {
+		return s.inRouteTimeoutTx(func(sess *Session) error {
+			return sess.SelectRaw(ctx, dest, query, args...)
+		})
+	}
+
 	s.clearSliceIfPossible(dest)
 	query, err := s.ReplacePlaceholders(query)
 	if err != nil {
 		return errors.Wrap(err, "replace placeholders failed")
 	}
 
+	if err = s.setRouteStatementTimeout(ctx); err != nil {
+		return err
+	}
+
 	start := time.Now()
 	err = s.conn().SelectContext(ctx, dest, query, args...)
 	s.log(ctx, "select", start, query, args)
@@ -411,10 +579,27 @@ func (s *Session) conn() Conn {
 	return s.DB
 }
 
+// maxSlowQueryLength is the number of characters of a slow query's SQL
+// included in the log line.
+const maxSlowQueryLength = 200
+
 func (s *Session) log(ctx context.Context, typ string, start time.Time, query string, args []interface{}) {
+	dur := time.Since(start)
 	log.
 		WithField("args", args).
 		WithField("sql", query).
-		WithField("dur", time.Since(start).String()).
+		WithField("dur", dur.String()).
 		Debugf("sql: %s", typ)
+
+	if s.SlowQueryThreshold > 0 && dur > s.SlowQueryThreshold {
+		if len(query) > maxSlowQueryLength {
+			query = query[:maxSlowQueryLength] + "..."
+		}
+		log.
+			WithField("query_type", getQueryType(ctx, sq.Expr(query))).
+			WithField("route", contextRoute(ctx)).
+			WithField("sql", query).
+			WithField("dur", dur.String()).
+			Warnf("sql: slow %s", typ)
+	}
 }
@@ -2,10 +2,16 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"io/ioutil"
 	"testing"
 	"time"
 
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
 	"github.com/stellar/go/support/db/dbtest"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -160,3 +166,206 @@ func TestIdleTransactionTimeout(t *testing.T) {
 	err = sess.GetRaw(context.Background(), &count, "SELECT COUNT(*) FROM people")
 	assert.ErrorIs(err, ErrBadConnection)
 }
+
+func TestRouteStatementTimeouts(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	db := dbtest.Postgres(t).Load(testSchema)
+	defer db.Close()
+
+	sess, err := Open(db.Dialect, db.DSN, StatementTimeout(50*time.Millisecond))
+	require.NoError(err)
+	defer sess.Close()
+	sess.RouteStatementTimeouts = map[string]time.Duration{
+		"/heavy": 3 * time.Second,
+	}
+
+	heavyCtx := context.WithValue(context.Background(), &RouteContextKey, "/heavy")
+	lightCtx := context.WithValue(context.Background(), &RouteContextKey, "/light")
+
+	// the configured route gets its elevated timeout within a transaction
+	var timeout string
+	require.NoError(sess.Begin())
+	require.NoError(sess.GetRaw(heavyCtx, &timeout, "SHOW statement_timeout"))
+	assert.Equal("3s", timeout)
+	var count int
+	assert.NoError(sess.GetRaw(heavyCtx, &count, "SELECT COUNT(*) FROM people WHERE pg_sleep(0.1) IS NOT NULL"))
+	require.NoError(sess.Rollback())
+
+	// other routes use the default
+	require.NoError(sess.Begin())
+	require.NoError(sess.GetRaw(lightCtx, &timeout, "SHOW statement_timeout"))
+	assert.Equal("50ms", timeout)
+	err = sess.GetRaw(lightCtx, &count, "SELECT COUNT(*) FROM people WHERE pg_sleep(0.1) IS NOT NULL")
+	assert.ErrorIs(err, ErrStatementTimeout)
+	require.NoError(sess.Rollback())
+
+	// the timeout doesn't outlive the transaction
+	require.NoError(sess.GetRaw(context.Background(), &timeout, "SHOW statement_timeout"))
+	assert.Equal("50ms", timeout)
+
+	// get, select and exec get the route timeout outside of a transaction too
+	require.NoError(sess.GetRaw(heavyCtx, &timeout, "SHOW statement_timeout"))
+	assert.Equal("3s", timeout)
+	assert.NoError(sess.GetRaw(heavyCtx, &count, "SELECT COUNT(*) FROM people WHERE pg_sleep(0.1) IS NOT NULL"))
+	var counts []int
+	assert.NoError(sess.SelectRaw(heavyCtx, &counts, "SELECT COUNT(*) FROM people WHERE pg_sleep(0.1) IS NOT NULL"))
+	_, err = sess.ExecRaw(heavyCtx, "SELECT pg_sleep(0.1)")
+	assert.NoError(err)
+	err = sess.GetRaw(lightCtx, &count, "SELECT COUNT(*) FROM people WHERE pg_sleep(0.1) IS NOT NULL")
+	assert.ErrorIs(err, ErrStatementTimeout)
+	require.NoError(sess.GetRaw(context.Background(), &timeout, "SHOW statement_timeout"))
+	assert.Equal("50ms", timeout)
+	assert.Nil(sess.GetTx())
+
+	// query only gets it within a transaction as its rows outlive the call
+	rows, err := sess.QueryRaw(heavyCtx, "SHOW statement_timeout")
+	require.NoError(err)
+	require.True(rows.Next())
+	require.NoError(rows.Scan(&timeout))
+	require.NoError(rows.Close())
+	assert.Equal("50ms", timeout)
+}
+
+func TestSlowQueryThreshold(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	db := dbtest.Postgres(t).Load(testSchema)
+	defer db.Close()
+
+	sess := &Session{DB: db.Open(), SlowQueryThreshold: 50 * time.Millisecond}
+	defer sess.DB.Close()
+	ctx := context.WithValue(context.Background(), &RouteContextKey, "/people")
+
+	// fast queries aren't logged
+	done := log.DefaultLogger.StartTest(log.WarnLevel)
+	var count int
+	require.NoError(sess.GetRaw(ctx, &count, "SELECT COUNT(*) FROM people WHERE name = ?", "bartek"))
+	assert.Empty(done())
+
+	// slow queries are logged without their bound arguments
+	done = log.DefaultLogger.StartTest(log.WarnLevel)
+	require.NoError(sess.GetRaw(ctx, &count, "SELECT COUNT(*) FROM people WHERE name = ? AND pg_sleep(0.1) IS NOT NULL", "bartek"))
+	logged := done()
+	require.Len(logged, 1)
+	assert.Equal("sql: slow get", logged[0].Message)
+	assert.Equal(SelectQueryType, logged[0].Data["query_type"])
+	assert.Equal("/people", logged[0].Data["route"])
+	assert.Equal("SELECT COUNT(*) FROM people WHERE name = $1 AND pg_sleep(0.1) IS NOT NULL", logged[0].Data["sql"])
+	assert.NotContains(logged[0].Data, "args")
+}
+
+func TestDeleteRangeBatched(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	db := dbtest.Postgres(t).Load(testSchema)
+	defer db.Close()
+
+	sess := &Session{DB: db.Open()}
+	defer sess.DB.Close()
+	ctx := context.Background()
+
+	_, err := sess.ExecRaw(ctx, "INSERT INTO people (name, hunger_level) VALUES ('a', 1), ('b', 2), ('c', 3), ('d', 4), ('e', 5)")
+	require.NoError(err)
+
+	// invalid batch size
+	assert.EqualError(sess.DeleteRangeBatched(ctx, 0, 100, "people", "hunger_level", 0), "batch size must be greater than 0")
+
+	// cancellation before the first batch leaves all rows in place
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(sess.DeleteRangeBatched(cancelledCtx, 0, 100, "people", "hunger_level", 2), context.Canceled)
+	var count int
+	require.NoError(sess.GetRaw(ctx, &count, "SELECT COUNT(*) FROM people"))
+	assert.Equal(8, count)
+
+	// the rows DeleteRange leaves on the same fixture
+	var expected []string
+	require.NoError(sess.Begin())
+	require.NoError(sess.DeleteRange(ctx, 2, 11, "people", "hunger_level"))
+	require.NoError(sess.SelectRaw(ctx, &expected, "SELECT name FROM people ORDER BY name"))
+	require.NoError(sess.Rollback())
+	assert.Equal([]string{"a", "scott"}, expected)
+
+	// cancellation after the first batch keeps the rows it deleted
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logger := log.New()
+	logger.SetLevel(log.DebugLevel)
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(&cancelOnExecHook{cancel: cancel})
+	err = func() error {
+		defer func(defaultLogger *log.Entry) { log.DefaultLogger = defaultLogger }(log.DefaultLogger)
+		log.DefaultLogger = logger
+		return sess.DeleteRangeBatched(batchCtx, 2, 11, "people", "hunger_level", 2)
+	}()
+	assert.ErrorIs(err, context.Canceled)
+	var names []string
+	require.NoError(sess.SelectRaw(ctx, &names, "SELECT name FROM people ORDER BY name"))
+	assert.Equal([]string{"a", "bartek", "d", "e", "jed", "scott"}, names)
+
+	// running again deletes the same rows as DeleteRange, in batches
+	require.NoError(sess.DeleteRangeBatched(ctx, 2, 11, "people", "hunger_level", 2))
+	names = nil
+	require.NoError(sess.SelectRaw(ctx, &names, "SELECT name FROM people ORDER BY name"))
+	assert.Equal(expected, names)
+}
+
+// cancelOnExecHook cancels a context once the first exec statement has run.
+type cancelOnExecHook struct {
+	cancel context.CancelFunc
+}
+
+func (h *cancelOnExecHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *cancelOnExecHook) Fire(entry *logrus.Entry) error {
+	if entry.Message == "sql: exec" {
+		h.cancel()
+	}
+	return nil
+}
+
+func TestReplaceWithKnownErrorConcurrencyFailures(t *testing.T) {
+	sess := &Session{}
+	ctx := context.Background()
+
+	err := errors.Wrap(&pq.Error{Code: "40001"}, "exec failed")
+	assert.Equal(t, ErrSerializationFailure, sess.replaceWithKnownError(err, ctx))
+
+	err = errors.Wrap(&pq.Error{Code: "40P01"}, "exec failed")
+	assert.Equal(t, ErrDeadlock, sess.replaceWithKnownError(err, ctx))
+
+	err = &pq.Error{Code: "23505"}
+	assert.NoError(t, sess.replaceWithKnownError(err, ctx))
+}
+
+func TestSerializationFailure(t *testing.T) {
+	require := require.New(t)
+	db := dbtest.Postgres(t).Load(testSchema)
+	defer db.Close()
+
+	sess1 := &Session{DB: db.Open()}
+	defer sess1.DB.Close()
+	sess2 := &Session{DB: db.Open()}
+	defer sess2.DB.Close()
+	ctx := context.Background()
+
+	txOptions := &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+	require.NoError(sess1.BeginTx(txOptions))
+	defer sess1.Rollback()
+	require.NoError(sess2.BeginTx(txOptions))
+	defer sess2.Rollback()
+
+	var hungerLevel int
+	require.NoError(sess1.GetRaw(ctx, &hungerLevel, "SELECT hunger_level FROM people WHERE name = 'jed'"))
+	require.NoError(sess2.GetRaw(ctx, &hungerLevel, "SELECT hunger_level FROM people WHERE name = 'jed'"))
+
+	_, err := sess1.ExecRaw(ctx, "UPDATE people SET hunger_level = 11 WHERE name = 'jed'")
+	require.NoError(err)
+	require.NoError(sess1.Commit())
+
+	_, err = sess2.ExecRaw(ctx, "UPDATE people SET hunger_level = 12 WHERE name = 'jed'")
+	assert.ErrorIs(t, err, ErrSerializationFailure)
+}